@@ -2,8 +2,22 @@ package main
 
 import (
 	"chirpy/internal/server"
+	"flag"
+	"fmt"
+	"log"
 )
 
 func main() {
+	promoteAdmin := flag.String("promote-admin", "", "grant the admin role to the user with this email and exit")
+	flag.Parse()
+
+	if *promoteAdmin != "" {
+		if err := server.PromoteAdmin(*promoteAdmin); err != nil {
+			log.Fatalf("promoting admin: %v", err)
+		}
+		fmt.Printf("%s is now an admin\n", *promoteAdmin)
+		return
+	}
+
 	server.StartApp(":8080")
 }
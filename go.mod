@@ -10,3 +10,10 @@ require (
 )
 
 require github.com/golang-jwt/jwt/v5 v5.2.1
+
+require (
+	github.com/gorilla/websocket v1.5.3
+	golang.org/x/text v0.21.0
+)
+
+require golang.org/x/sys v0.28.0 // indirect
@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strings"
+)
+
+// What a personal access token is allowed to do
+const (
+	ScopeChirpsRead   = "chirps:read"
+	ScopeChirpsWrite  = "chirps:write"
+	ScopeProfileWrite = "profile:write"
+	ScopeWebhooks     = "webhooks:manage"
+)
+
+var Scopes = []string{ScopeChirpsRead, ScopeChirpsWrite, ScopeProfileWrite, ScopeWebhooks}
+
+const (
+	patPrefix = "chirpy_pat_"
+	// Characters of the random part shown in listings
+	patVisibleChars = 8
+)
+
+// Creates a personal access token. Only the hash is stored; the prefix is
+// the start of the token, shown so users can tell their tokens apart.
+func MakePersonalAccessToken() (token, prefix, hash string, err error) {
+	data := make([]byte, 32)
+	_, err = rand.Read(data)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	token = patPrefix + hex.EncodeToString(data)
+	return token, token[:len(patPrefix)+patVisibleChars], HashAccessToken(token), nil
+}
+
+// Tokens are long and random, so a fast hash is enough to look them up by
+func HashAccessToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Tells personal access tokens apart from JWTs in the Authorization header
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, patPrefix)
+}
+
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(Scopes, scope) {
+			return errors.New("unknown scope " + scope)
+		}
+	}
+	return nil
+}
@@ -10,58 +10,196 @@ import (
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
-// Hash password using bcrypt
-func HashPassword(password string) (string, error) {
-	hashedPass, err := bcrypt.GenerateFromPassword([]byte(password), 1)
-	if err != nil {
-		return password, err
-	}
-
-	return string(hashedPass), err
-
+// Claims carried by Chirpy access tokens
+type Claims struct {
+	Role Role `json:"role"`
+	// The refresh token family the access token was minted from
+	SessionID string `json:"sid,omitempty"`
+	// Set on tokens that are not access tokens, such as 2FA challenges
+	Purpose string `json:"pur,omitempty"`
+	Email   string `json:"email,omitempty"`
+	// Set on tokens issued to OAuth clients, which may only use the
+	// space separated scopes the user granted
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	jwt.RegisteredClaims
 }
 
-// Compare hash to password
-func CheckPasswordHash(hash, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+// What a validated access token says about its bearer
+type AccessToken struct {
+	UserID    uuid.UUID
+	Role      Role
+	SessionID uuid.NullUUID
+	// Valid for tokens issued to an OAuth client, limited to Scopes
+	ClientID  uuid.NullUUID
+	Scopes    []string
+	ExpiresAt time.Time
 }
 
 // Create a new JWT
-func MakeJWT(userID uuid.UUID, tokenSecret string, expiresIn time.Duration) (string, error) {
-
-	jwtToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
-		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn).UTC()),
-		Subject:   userID.String(),
+func MakeJWT(userID uuid.UUID, role Role, sessionID uuid.UUID, keys *KeySet, expiresIn time.Duration) (string, error) {
+
+	return keys.sign(Claims{
+		Role:      role,
+		SessionID: sessionID.String(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    keys.Issuer,
+			Audience:  jwt.ClaimStrings{keys.Audience},
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn).UTC()),
+			Subject:   userID.String(),
+		},
 	})
+}
 
-	return jwtToken.SignedString([]byte(tokenSecret))
+// Create a JWT for an OAuth client acting on the user's behalf. It never
+// carries more than the user role, whatever the user's own role is.
+func MakeOAuthJWT(userID, sessionID, clientID uuid.UUID, scopes []string, keys *KeySet, expiresIn time.Duration) (string, error) {
+	return keys.sign(Claims{
+		Role:      RoleUser,
+		SessionID: sessionID.String(),
+		ClientID:  clientID.String(),
+		Scope:     FormatScope(scopes),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    keys.Issuer,
+			Audience:  jwt.ClaimStrings{keys.Audience},
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn).UTC()),
+			Subject:   userID.String(),
+		},
+	})
 }
 
 // Get userID from JWT
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	token, err := jwt.ParseWithClaims(
+func ValidateJWT(tokenString string, keys *KeySet) (uuid.UUID, error) {
+	token, err := ParseJWT(tokenString, keys)
+	return token.UserID, err
+}
+
+// Get the user, role and session from JWT. The token must name a key in
+// the set in its kid header and be issued by and for this server, so
+// tokens minted before key sets existed are rejected and their users have
+// to log in again. A token without a role or session claim belongs to a
+// regular user outside of any session.
+func ParseJWT(tokenString string, keys *KeySet) (AccessToken, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(
 		tokenString,
-		&jwt.RegisteredClaims{},
-		func(token *jwt.Token) (interface{}, error) {
-			return []byte(tokenSecret), nil
-		})
+		claims,
+		keys.keyfunc,
+		keys.parserOptions()...)
+
+	if err != nil {
+		return AccessToken{}, err
+	}
+
+	if claims.Purpose != "" {
+		return AccessToken{}, errors.New("not an access token")
+	}
+
+	token := AccessToken{Role: RoleUser, ExpiresAt: claims.ExpiresAt.Time}
+	token.UserID, err = uuid.Parse(claims.Subject)
+	if err != nil {
+		return AccessToken{}, err
+	}
+
+	if claims.Role != "" {
+		token.Role, err = ParseRole(string(claims.Role))
+		if err != nil {
+			return AccessToken{}, err
+		}
+	}
+
+	if claims.SessionID != "" {
+		token.SessionID.UUID, err = uuid.Parse(claims.SessionID)
+		if err != nil {
+			return AccessToken{}, err
+		}
+		token.SessionID.Valid = true
+	}
+
+	if claims.ClientID != "" {
+		token.ClientID.UUID, err = uuid.Parse(claims.ClientID)
+		if err != nil {
+			return AccessToken{}, err
+		}
+		token.ClientID.Valid = true
+		token.Scopes = ParseScope(claims.Scope)
+	}
+
+	return token, nil
+}
+
+// Purposes of tokens that are not access tokens
+const (
+	PurposeChallenge     = "2fa"
+	PurposePasswordReset = "password_reset"
+	PurposeVerifyEmail   = "verify_email"
+	PurposeStream        = "stream"
+)
+
+// A token issued for one purpose other than API access
+type PurposeToken struct {
+	ID     uuid.UUID
+	UserID uuid.UUID
+	Email  string
+	// When the token stops being accepted
+	ExpiresAt time.Time
+}
 
+// Signs a token that is only accepted for the given purpose. The token's
+// ID lets callers make it single use; email, when set, ties the token to
+// the address it was sent to.
+func MakePurposeJWT(userID uuid.UUID, purpose, email string, keys *KeySet, expiresIn time.Duration) (string, error) {
+	return keys.sign(Claims{
+		Purpose: purpose,
+		Email:   email,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    keys.Issuer,
+			Audience:  jwt.ClaimStrings{keys.Audience},
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn).UTC()),
+			Subject:   userID.String(),
+		},
+	})
+}
+
+func ValidatePurposeJWT(tokenString, purpose string, keys *KeySet) (PurposeToken, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, keys.keyfunc, keys.parserOptions()...)
 	if err != nil {
-		return uuid.Nil, err
+		return PurposeToken{}, err
 	}
 
-	userID, err := token.Claims.GetSubject()
+	if claims.Purpose != purpose {
+		return PurposeToken{}, errors.New("token is not for " + purpose)
+	}
 
+	token := PurposeToken{Email: claims.Email, ExpiresAt: claims.ExpiresAt.Time}
+	token.UserID, err = uuid.Parse(claims.Subject)
 	if err != nil {
-		return uuid.Nil, err
+		return PurposeToken{}, err
 	}
+	token.ID, err = uuid.Parse(claims.ID)
+	if err != nil {
+		return PurposeToken{}, err
+	}
+	return token, nil
+}
 
-	return uuid.Parse(userID)
+// A token proving the password step of a two-factor login succeeded. It
+// cannot be used as an access token.
+func MakeChallengeJWT(userID uuid.UUID, keys *KeySet, expiresIn time.Duration) (string, error) {
+	return MakePurposeJWT(userID, PurposeChallenge, "", keys, expiresIn)
+}
+
+// Get userID from a challenge token
+func ValidateChallengeJWT(tokenString string, keys *KeySet) (uuid.UUID, error) {
+	token, err := ValidatePurposeJWT(tokenString, PurposeChallenge, keys)
+	return token.UserID, err
 }
 
 // Returns the "Authorization" token of the request
@@ -74,7 +212,7 @@ func GetBearerToken(headers http.Header) (string, error) {
 
 	tokenStrings := strings.Split(tokenString, " ")
 
-	if len(tokenString) < 2 {
+	if len(tokenStrings) < 2 {
 		return "", errors.New("auth header malformed")
 	}
 
@@ -91,19 +229,3 @@ func MakeRefreshToken() (string, error) {
 
 	return hex.EncodeToString(data), nil
 }
-
-func GetAPIKey(headers http.Header) (string, error) {
-	tokenString := headers.Get("Authorization")
-
-	if tokenString == "" {
-		return "", errors.New("auth header not present")
-	}
-
-	tokenStrings := strings.Split(tokenString, " ")
-
-	if len(tokenString) < 2 {
-		return "", errors.New("auth header malformed")
-	}
-
-	return tokenStrings[1], nil
-}
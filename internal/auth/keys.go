@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// A key tokens are signed or verified with, identified by the kid header.
+// Keys loaded from a public key file can only verify.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	signing interface{}
+	verify  interface{}
+}
+
+func (k *Key) CanSign() bool {
+	return k.signing != nil
+}
+
+// Builds a key from a PEM block holding an RSA or Ed25519 private key
+// (PKCS #1 or PKCS #8) or a PKIX public key
+func ParseKey(id string, pemBytes []byte) (*Key, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, errors.New("no PEM data in key " + id)
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, errors.New("unsupported PEM block " + block.Type + " in key " + id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, signing: key, verify: &key.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, verify: key}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, signing: key, verify: key.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, verify: key}, nil
+	}
+	return nil, errors.New("key " + id + " is not an RSA or Ed25519 key")
+}
+
+// A symmetric HS256 key. It is never published in the JWKS, so only this
+// server can verify tokens signed with it.
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, signing: secret, verify: secret}
+}
+
+// The keys tokens are signed and verified with. One key signs new tokens;
+// every key in the set verifies, so a retired key keeps being accepted
+// until the tokens it signed have expired.
+type KeySet struct {
+	Issuer   string
+	Audience string
+	signer   *Key
+	keys     map[string]*Key
+}
+
+func NewKeySet(issuer, audience string) *KeySet {
+	return &KeySet{Issuer: issuer, Audience: audience, keys: map[string]*Key{}}
+}
+
+// Adds a verification key, making it the signing key when sign is set
+func (ks *KeySet) Add(key *Key, sign bool) error {
+	if _, ok := ks.keys[key.ID]; ok {
+		return errors.New("duplicate key id " + key.ID)
+	}
+	if sign && !key.CanSign() {
+		return errors.New("key " + key.ID + " has no private key to sign with")
+	}
+	ks.keys[key.ID] = key
+	if sign {
+		ks.signer = key
+	}
+	return nil
+}
+
+// Loads every *.pem file in dir, using the file name without the
+// extension as the kid. The key named signingID signs new tokens; when it
+// is empty the directory must contain exactly one private key.
+func LoadKeySet(dir, signingID, issuer, audience string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []*Key{}
+	for _, path := range paths {
+		dat, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := ParseKey(strings.TrimSuffix(filepath.Base(path), ".pem"), dat)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if signingID == "" {
+		for _, key := range keys {
+			if !key.CanSign() {
+				continue
+			}
+			if signingID != "" {
+				return nil, errors.New("several private keys in " + dir + ", choose the signing key by kid")
+			}
+			signingID = key.ID
+		}
+	}
+
+	ks := NewKeySet(issuer, audience)
+	for _, key := range keys {
+		if err := ks.Add(key, key.ID == signingID); err != nil {
+			return nil, err
+		}
+	}
+	if ks.signer == nil {
+		return nil, errors.New("no signing key " + signingID + " in " + dir)
+	}
+	return ks, nil
+}
+
+func (ks *KeySet) sign(claims jwt.Claims) (string, error) {
+	if ks.signer == nil {
+		return "", errors.New("no signing key configured")
+	}
+	token := jwt.NewWithClaims(ks.signer.Method, claims)
+	token.Header["kid"] = ks.signer.ID
+	return token.SignedString(ks.signer.signing)
+}
+
+// Picks the verification key named by the token's kid, refusing tokens
+// whose alg does not match the key
+func (ks *KeySet) keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key " + kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method " + token.Method.Alg())
+	}
+	return key.verify, nil
+}
+
+func (ks *KeySet) parserOptions() []jwt.ParserOption {
+	methods := []string{}
+	for _, key := range ks.keys {
+		if !slices.Contains(methods, key.Method.Alg()) {
+			methods = append(methods, key.Method.Alg())
+		}
+	}
+	return []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithIssuer(ks.Issuer),
+		jwt.WithAudience(ks.Audience),
+		jwt.WithExpirationRequired(),
+	}
+}
+
+// A JSON Web Key as served from /.well-known/jwks.json
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// The public halves of the asymmetric keys in the set
+func (ks *KeySet) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	encode := base64.RawURLEncoding.EncodeToString
+	for _, key := range ks.keys {
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch pub := key.verify.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encode(pub.N.Bytes())
+			jwk.E = encode(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = encode(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	sort.Slice(set.Keys, func(i, j int) bool { return set.Keys[i].Kid < set.Keys[j].Kid })
+	return set
+}
@@ -0,0 +1,335 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+type testKeys struct {
+	rsa        *rsa.PrivateKey
+	ed25519    ed25519.PrivateKey
+	rsaPKCS1   []byte
+	rsaPKCS8   []byte
+	rsaPublic  []byte
+	edPKCS8    []byte
+	edPublic   []byte
+	notAKeyPEM []byte
+}
+
+func generateTestKeys(t *testing.T) testKeys {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encode := func(blockType string, der []byte, err error) []byte {
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	}
+	rsaPKCS8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	rsaPKCS8PEM := encode("PRIVATE KEY", rsaPKCS8, err)
+	rsaPublic, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	rsaPublicPEM := encode("PUBLIC KEY", rsaPublic, err)
+	edPKCS8, err := x509.MarshalPKCS8PrivateKey(edKey)
+	edPKCS8PEM := encode("PRIVATE KEY", edPKCS8, err)
+	edPublic, err := x509.MarshalPKIXPublicKey(edKey.Public())
+	edPublicPEM := encode("PUBLIC KEY", edPublic, err)
+
+	return testKeys{
+		rsa:        rsaKey,
+		ed25519:    edKey,
+		rsaPKCS1:   pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}),
+		rsaPKCS8:   rsaPKCS8PEM,
+		rsaPublic:  rsaPublicPEM,
+		edPKCS8:    edPKCS8PEM,
+		edPublic:   edPublicPEM,
+		notAKeyPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("junk")}),
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	keys := generateTestKeys(t)
+
+	tests := []struct {
+		name        string
+		pem         []byte
+		wantMethod  jwt.SigningMethod
+		wantCanSign bool
+		wantErr     bool
+	}{
+		{"RSA PKCS #1", keys.rsaPKCS1, jwt.SigningMethodRS256, true, false},
+		{"RSA PKCS #8", keys.rsaPKCS8, jwt.SigningMethodRS256, true, false},
+		{"RSA public", keys.rsaPublic, jwt.SigningMethodRS256, false, false},
+		{"Ed25519 PKCS #8", keys.edPKCS8, jwt.SigningMethodEdDSA, true, false},
+		{"Ed25519 public", keys.edPublic, jwt.SigningMethodEdDSA, false, false},
+		{"not PEM", []byte("not a key"), nil, false, true},
+		{"unsupported block", keys.notAKeyPEM, nil, false, true},
+		{"corrupt block", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("junk")}), nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := ParseKey("test", tt.pem)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseKey() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if key.ID != "test" || key.Method != tt.wantMethod || key.CanSign() != tt.wantCanSign {
+				t.Errorf("ParseKey() = %s %s can sign %v, want test %s can sign %v",
+					key.ID, key.Method.Alg(), key.CanSign(), tt.wantMethod.Alg(), tt.wantCanSign)
+			}
+		})
+	}
+}
+
+func writeKeyFiles(t *testing.T, files map[string][]byte) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadKeySet(t *testing.T) {
+	keys := generateTestKeys(t)
+
+	tests := []struct {
+		name       string
+		files      map[string][]byte
+		signingID  string
+		wantSigner string
+		wantErr    bool
+	}{
+		{
+			name:       "single private key signs",
+			files:      map[string][]byte{"2024.pem": keys.rsaPKCS1, "old.pem": keys.edPublic},
+			wantSigner: "2024",
+		},
+		{
+			name:       "named signing key",
+			files:      map[string][]byte{"2024.pem": keys.rsaPKCS1, "2025.pem": keys.edPKCS8},
+			signingID:  "2025",
+			wantSigner: "2025",
+		},
+		{
+			name:    "several private keys and none named",
+			files:   map[string][]byte{"2024.pem": keys.rsaPKCS1, "2025.pem": keys.edPKCS8},
+			wantErr: true,
+		},
+		{
+			name:      "named key missing",
+			files:     map[string][]byte{"2024.pem": keys.rsaPKCS1},
+			signingID: "2025",
+			wantErr:   true,
+		},
+		{
+			name:      "named key is public",
+			files:     map[string][]byte{"2024.pem": keys.rsaPKCS1, "2025.pem": keys.edPublic},
+			signingID: "2025",
+			wantErr:   true,
+		},
+		{
+			name:    "no keys",
+			files:   map[string][]byte{"README.txt": []byte("keys go here")},
+			wantErr: true,
+		},
+		{
+			name:    "unreadable key",
+			files:   map[string][]byte{"2024.pem": keys.rsaPKCS1, "bad.pem": []byte("junk")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeKeyFiles(t, tt.files)
+			set, err := LoadKeySet(dir, tt.signingID, "chirpy", "chirpy-api")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadKeySet() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if set.signer.ID != tt.wantSigner {
+				t.Errorf("signer = %s, want %s", set.signer.ID, tt.wantSigner)
+			}
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	keys := generateTestKeys(t)
+	userID := uuid.New()
+
+	oldKey, err := ParseKey("old", keys.rsaPKCS1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newKey, err := ParseKey("new", keys.edPKCS8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := NewKeySet("chirpy", "chirpy-api")
+	if err := before.Add(oldKey, true); err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := MakeJWT(userID, RoleUser, uuid.New(), before, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new key signs while the old one still verifies
+	after := NewKeySet("chirpy", "chirpy-api")
+	if err := after.Add(newKey, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := after.Add(oldKey, false); err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := MakeJWT(userID, RoleUser, uuid.New(), after, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{"old": oldToken, "new": newToken} {
+		if got, err := ValidateJWT(token, after); err != nil || got != userID {
+			t.Errorf("%s token: ValidateJWT() = %s, %v, want %s", name, got, err, userID)
+		}
+	}
+	if _, err := ValidateJWT(newToken, before); err == nil {
+		t.Error("a token signed with a key missing from the set was accepted")
+	}
+}
+
+func TestKeySetVerifyOnlyHMAC(t *testing.T) {
+	keys := generateTestKeys(t)
+	userID := uuid.New()
+
+	legacy := NewKeySet("chirpy", "chirpy-api")
+	if err := legacy.Add(NewHMACKey("secret", []byte("s3cr3t")), true); err != nil {
+		t.Fatal(err)
+	}
+	token, err := MakeJWT(userID, RoleUser, uuid.New(), legacy, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaKey, err := ParseKey("2024", keys.rsaPKCS1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := NewKeySet("chirpy", "chirpy-api")
+	if err := set.Add(rsaKey, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.Add(NewHMACKey("secret", []byte("s3cr3t")), false); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := ValidateJWT(token, set); err != nil || got != userID {
+		t.Errorf("ValidateJWT() = %s, %v, want %s", got, err, userID)
+	}
+	if set.signer.ID != "2024" {
+		t.Errorf("signer = %s, want the RSA key", set.signer.ID)
+	}
+	if jwks := set.JWKS(); len(jwks.Keys) != 1 || jwks.Keys[0].Kid != "2024" {
+		t.Errorf("JWKS() = %+v, want only the RSA key", jwks)
+	}
+}
+
+func TestKeySetRejects(t *testing.T) {
+	keys := generateTestKeys(t)
+	rsaKey, err := ParseKey("2024", keys.rsaPKCS1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := NewKeySet("chirpy", "chirpy-api")
+	if err := set.Add(rsaKey, true); err != nil {
+		t.Fatal(err)
+	}
+
+	claims := func(issuer, audience string, expiresIn time.Duration) Claims {
+		return Claims{RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   uuid.NewString(),
+		}}
+	}
+	signed := func(method jwt.SigningMethod, kid string, key interface{}, c Claims) string {
+		token := jwt.NewWithClaims(method, c)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	valid := claims("chirpy", "chirpy-api", time.Hour)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"no kid", signed(jwt.SigningMethodRS256, "", keys.rsa, valid)},
+		{"unknown kid", signed(jwt.SigningMethodRS256, "2023", keys.rsa, valid)},
+		{"HS256 with the public key as secret", signed(jwt.SigningMethodHS256, "2024", x509.MarshalPKCS1PublicKey(&keys.rsa.PublicKey), valid)},
+		{"signed by another key", signed(jwt.SigningMethodEdDSA, "2024", keys.ed25519, valid)},
+		{"wrong issuer", signed(jwt.SigningMethodRS256, "2024", keys.rsa, claims("other", "chirpy-api", time.Hour))},
+		{"wrong audience", signed(jwt.SigningMethodRS256, "2024", keys.rsa, claims("chirpy", "other-api", time.Hour))},
+		{"expired", signed(jwt.SigningMethodRS256, "2024", keys.rsa, claims("chirpy", "chirpy-api", -time.Minute))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseJWT(tt.token, set); err == nil {
+				t.Error("ParseJWT() accepted the token")
+			}
+		})
+	}
+}
+
+func TestKeySetAdd(t *testing.T) {
+	keys := generateTestKeys(t)
+	public, err := ParseKey("public", keys.edPublic)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	set := NewKeySet("chirpy", "chirpy-api")
+	if err := set.Add(public, true); err == nil {
+		t.Error("Add() made a public key the signing key")
+	}
+	if err := set.Add(public, false); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := set.Add(NewHMACKey("public", []byte("s3cr3t")), false); err == nil {
+		t.Error("Add() accepted a duplicate key id")
+	}
+	if _, err := MakeJWT(uuid.New(), RoleUser, uuid.New(), set, time.Hour); err == nil {
+		t.Error("MakeJWT() signed with a set that has no signing key")
+	}
+}
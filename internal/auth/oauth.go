@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+	"strings"
+)
+
+// Code verifiers are 43 to 128 unreserved characters (RFC 7636 section 4.1)
+var codeVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// Checks a PKCE code verifier against the S256 challenge sent with the
+// authorization request
+func VerifyPKCE(verifier, challenge string) bool {
+	if !codeVerifierPattern.MatchString(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// S256 challenges are the base64url encoding of a SHA-256 sum
+func ValidCodeChallenge(challenge string) bool {
+	return len(challenge) == base64.RawURLEncoding.EncodedLen(sha256.Size) &&
+		codeVerifierPattern.MatchString(challenge)
+}
+
+// OAuth scopes travel as one space separated string
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
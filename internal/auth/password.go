@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cost parameters for argon2id. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// The second recommended option of RFC 9106
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 4,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var argon2Params = DefaultArgon2Params
+
+// Changes the parameters new hashes are made with. Existing hashes keep
+// verifying and are upgraded by NeedsRehash callers on the next login.
+func SetArgon2Params(params Argon2Params) {
+	argon2Params = params
+}
+
+var phcEncoding = base64.RawStdEncoding
+
+// Hash password using argon2id, encoded in the PHC string format:
+// $argon2id$v=19$m=65536,t=3,p=4$<salt>$<hash>
+func HashPassword(password string) (string, error) {
+	params := argon2Params
+	salt := make([]byte, params.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+// Compare hash to password. Accepts argon2id hashes and the bcrypt hashes
+// Chirpy used to store.
+func CheckPasswordHash(hash, password string) error {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	}
+
+	params, salt, key, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return errors.New("password does not match")
+	}
+	return nil
+}
+
+// Reports whether the hash uses an older algorithm or weaker parameters
+// than new hashes are made with
+func NeedsRehash(hash string) bool {
+	params, _, _, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+
+	current := argon2Params
+	return params.Memory < current.Memory ||
+		params.Iterations < current.Iterations ||
+		params.KeyLength < current.KeyLength ||
+		params.SaltLength < current.SaltLength
+}
+
+func decodeArgon2Hash(hash string) (Argon2Params, []byte, []byte, error) {
+	params := Argon2Params{}
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, errors.New("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, err
+	}
+
+	salt, err := phcEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := phcEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 256
+)
+
+// A few of the most common passwords that meet the length requirement
+var commonPasswords = map[string]bool{
+	"password": true, "password1": true, "password123": true, "12345678": true,
+	"123456789": true, "1234567890": true, "qwertyuiop": true, "qwerty123": true,
+	"iloveyou": true, "sunshine": true, "princess": true, "football": true,
+	"baseball": true, "welcome1": true, "abc12345": true, "11111111": true,
+	"00000000": true, "letmein1": true, "trustno1": true, "superman": true,
+	"starwars": true, "whatever": true, "passw0rd": true, "chirpy123": true,
+}
+
+// Checks a new password against the password policy: 8 to 256
+// characters, not a well known password and not one of the user's own
+// details such as their email address
+func CheckPasswordStrength(password string, userInputs ...string) error {
+	length := utf8.RuneCountInString(password)
+	if length < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", minPasswordLength)
+	}
+	if length > maxPasswordLength {
+		return fmt.Errorf("password must be at most %d characters", maxPasswordLength)
+	}
+
+	lower := strings.ToLower(password)
+	if commonPasswords[lower] {
+		return errors.New("password is too common")
+	}
+
+	if strings.Count(lower, lower[:1]) == len(lower) {
+		return errors.New("password must not repeat a single character")
+	}
+
+	for _, input := range userInputs {
+		input = strings.ToLower(input)
+		local, _, _ := strings.Cut(input, "@")
+		if input != "" && (lower == input || lower == local) {
+			return errors.New("password must not be your email address or handle")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap parameters keep the tests fast
+var testArgon2Params = Argon2Params{
+	Memory:      1024,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+func withArgon2Params(t *testing.T, params Argon2Params) {
+	previous := argon2Params
+	SetArgon2Params(params)
+	t.Cleanup(func() { SetArgon2Params(previous) })
+}
+
+func TestHashPassword(t *testing.T) {
+	withArgon2Params(t, testArgon2Params)
+
+	hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("hash %q is not in the expected PHC format", hash)
+	}
+
+	other, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash == other {
+		t.Error("hashing the same password twice gave the same hash, the salt is not random")
+	}
+}
+
+func TestCheckPasswordHash(t *testing.T) {
+	withArgon2Params(t, testArgon2Params)
+
+	argon2Hash, err := HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("correct horse battery staple"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		wantErr  bool
+	}{
+		{"argon2id", argon2Hash, "correct horse battery staple", false},
+		{"argon2id wrong password", argon2Hash, "wrong horse battery staple", true},
+		{"bcrypt", string(bcryptHash), "correct horse battery staple", false},
+		{"bcrypt wrong password", string(bcryptHash), "wrong horse battery staple", true},
+		{"empty password", argon2Hash, "", true},
+		{"empty hash", "", "correct horse battery staple", true},
+		{"malformed argon2id", "$argon2id$v=19$m=1024", "correct horse battery staple", true},
+		{"unsupported version", strings.Replace(argon2Hash, "v=19", "v=16", 1), "correct horse battery staple", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPasswordHash(tt.hash, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckPasswordHash() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Hashes password with params, leaving the current parameters as they were
+func hashWith(t *testing.T, params Argon2Params, password string) string {
+	previous := argon2Params
+	SetArgon2Params(params)
+	defer SetArgon2Params(previous)
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+func TestNeedsRehash(t *testing.T) {
+	withArgon2Params(t, testArgon2Params)
+
+	stronger := testArgon2Params
+	stronger.Iterations = 2
+	lessMemory := testArgon2Params
+	lessMemory.Memory = 512
+	shorterKey := testArgon2Params
+	shorterKey.KeyLength = 16
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("correct horse battery staple"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		hash string
+		want bool
+	}{
+		{"current parameters", hashWith(t, testArgon2Params, "correct horse battery staple"), false},
+		{"stronger parameters", hashWith(t, stronger, "correct horse battery staple"), false},
+		{"less memory", hashWith(t, lessMemory, "correct horse battery staple"), true},
+		{"shorter key", hashWith(t, shorterKey, "correct horse battery staple"), true},
+		{"bcrypt", string(bcryptHash), true},
+		{"garbage", "not a hash", true},
+	}
+	for _, tt := range tests {
+		if got := NeedsRehash(tt.hash); got != tt.want {
+			t.Errorf("NeedsRehash(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckPasswordStrength(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		userInputs []string
+		wantErr    bool
+	}{
+		{"strong", "correct horse battery staple", nil, false},
+		{"exactly 8 characters", "k9#vLq2x", nil, false},
+		{"too short", "k9#vLq2", nil, true},
+		{"8 characters counted as runes", "ðŋßœøπåß", nil, false},
+		{"too long", strings.Repeat("ab", 129), nil, true},
+		{"common", "Password123", nil, true},
+		{"single repeated character", "aaaaaaaaaa", nil, true},
+		{"the email address", "walt@breakingbad.com", []string{"walt@breakingbad.com"}, true},
+		{"the email's local part", "heisenberg99", []string{"heisenberg99@breakingbad.com"}, true},
+		{"the handle", "HeisenbergFan", []string{"walt@breakingbad.com", "heisenbergfan"}, true},
+		{"unrelated to the inputs", "purple mountain majesty", []string{"walt@breakingbad.com", "heisenberg"}, false},
+		{"empty inputs are ignored", "correct horse battery staple", []string{""}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPasswordStrength(tt.password, tt.userInputs...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckPasswordStrength(%q) error = %v, want error %v", tt.password, err, tt.wantErr)
+			}
+		})
+	}
+}
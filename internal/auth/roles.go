@@ -0,0 +1,31 @@
+package auth
+
+import "errors"
+
+type Role string
+
+// Roles in increasing order of privilege
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+var roleRank = map[Role]int{
+	RoleUser:      1,
+	RoleModerator: 2,
+	RoleAdmin:     3,
+}
+
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := roleRank[role]; !ok {
+		return "", errors.New("unknown role " + s)
+	}
+	return role, nil
+}
+
+// Reports whether the role grants at least the privileges of required
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// How far a webhook's timestamp may be from the receiver's clock. Older
+// deliveries are refused so a captured one cannot be replayed later.
+const WebhookTolerance = 5 * time.Minute
+
+func webhookMAC(secret string, timestamp int64, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+// Signs a webhook body, returning the signature header value
+// "t=<unix time>,v1=<hex HMAC-SHA256 of "<unix time>.<body>">"
+func SignWebhook(secret string, body []byte, now time.Time) string {
+	timestamp := now.Unix()
+	return "t=" + strconv.FormatInt(timestamp, 10) + ",v1=" + hex.EncodeToString(webhookMAC(secret, timestamp, body))
+}
+
+// Checks a signature header made by SignWebhook. Senders rotating their
+// secret may send several v1 signatures; one matching is enough.
+func VerifyWebhook(secret, header string, body []byte, now time.Time, tolerance time.Duration) error {
+	if secret == "" {
+		return errors.New("no webhook secret configured")
+	}
+
+	var timestamp int64
+	signatures := [][]byte{}
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			t, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return errors.New("malformed signature timestamp")
+			}
+			timestamp = t
+		case "v1":
+			signature, err := hex.DecodeString(value)
+			if err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+	if timestamp == 0 || len(signatures) == 0 {
+		return errors.New("malformed signature header")
+	}
+
+	age := now.Sub(time.Unix(timestamp, 0))
+	if age > tolerance || age < -tolerance {
+		return errors.New("signature timestamp outside the tolerance")
+	}
+
+	expected := webhookMAC(secret, timestamp, body)
+	for _, signature := range signatures {
+		if hmac.Equal(signature, expected) {
+			return nil
+		}
+	}
+	return errors.New("signature mismatch")
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignWebhook(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"user.upgraded"}`)
+
+	signature := SignWebhook("whsec", body, now)
+	if !strings.HasPrefix(signature, "t=1700000000,v1=") || len(signature) != len("t=1700000000,v1=")+64 {
+		t.Errorf("SignWebhook() = %q, want t=1700000000,v1=<64 hex digits>", signature)
+	}
+	if SignWebhook("whsec", body, now) != signature {
+		t.Error("signing the same body twice gave different signatures")
+	}
+	if SignWebhook("other", body, now) == signature {
+		t.Error("signatures with different secrets match")
+	}
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"event":"user.upgraded"}`)
+	signature := SignWebhook("whsec", body, now)
+	_, v1, _ := strings.Cut(signature, ",")
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		body    string
+		now     time.Time
+		wantErr bool
+	}{
+		{"valid", "whsec", signature, string(body), now, false},
+		{"spaces after commas", "whsec", "t=1700000000, " + v1, string(body), now, false},
+		{"one of several signatures", "whsec", signature + ",v1=" + strings.Repeat("ab", 32), string(body), now, false},
+		{"within the tolerance", "whsec", signature, string(body), now.Add(WebhookTolerance), false},
+		{"too old", "whsec", signature, string(body), now.Add(WebhookTolerance + time.Second), true},
+		{"from the future", "whsec", signature, string(body), now.Add(-WebhookTolerance - time.Second), true},
+		{"wrong secret", "other", signature, string(body), now, true},
+		{"no secret configured", "", signature, string(body), now, true},
+		{"tampered body", "whsec", signature, `{"event":"user.upgraded","plan":"free"}`, now, true},
+		{"tampered timestamp", "whsec", "t=1700000001," + v1, string(body), now, true},
+		{"no timestamp", "whsec", v1, string(body), now, true},
+		{"malformed timestamp", "whsec", "t=soon," + v1, string(body), now, true},
+		{"no signature", "whsec", "t=1700000000", string(body), now, true},
+		{"signature not hex", "whsec", "t=1700000000,v1=zz", string(body), now, true},
+		{"empty header", "whsec", "", string(body), now, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := VerifyWebhook(tt.secret, tt.header, []byte(tt.body), tt.now, WebhookTolerance)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("VerifyWebhook() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
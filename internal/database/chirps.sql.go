@@ -7,29 +7,55 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const chirpHasReplies = `-- name: ChirpHasReplies :one
+SELECT EXISTS (
+    SELECT 1 FROM chirps WHERE in_reply_to = $1::uuid
+)
+`
+
+func (q *Queries) ChirpHasReplies(ctx context.Context, chirpID uuid.UUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, chirpHasReplies, chirpID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, status, published_at)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    CASE WHEN $4 = 'published' THEN NOW() END
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body      string
+	UserID    uuid.UUID
+	InReplyTo uuid.NullUUID
+	Status    string
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.Body,
+		arg.UserID,
+		arg.InReplyTo,
+		arg.Status,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +63,49 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishedAt,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of, published_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    $1,
+    $2::uuid,
+    NOW()
+)
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at
+`
+
+type CreateRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.UUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOf)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishedAt,
 	)
 	return i, err
 }
@@ -52,6 +121,31 @@ func (q *Queries) DeleteChirpById(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const deleteRechirp = `-- name: DeleteRechirp :exec
+DELETE FROM chirps
+WHERE user_id = $1 AND rechirp_of = $2::uuid
+`
+
+type DeleteRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.UUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) error {
+	_, err := q.db.ExecContext(ctx, deleteRechirp, arg.UserID, arg.RechirpOf)
+	return err
+}
+
+const deleteRechirpsOf = `-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps
+WHERE rechirp_of = $1::uuid
+`
+
+func (q *Queries) DeleteRechirpsOf(ctx context.Context, chirpID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, deleteRechirpsOf, chirpID)
+	return err
+}
+
 const dropChirps = `-- name: DropChirps :exec
 DELETE FROM chirps
 `
@@ -61,13 +155,350 @@ func (q *Queries) DropChirps(ctx context.Context) error {
 	return err
 }
 
-const getAllChirpsAsc = `-- name: GetAllChirpsAsc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
-ORDER BY created_at ASC
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.status, chirps.published_at, 1 AS depth FROM chirps
+    WHERE chirps.id = (SELECT in_reply_to FROM chirps WHERE chirps.id = $1)
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.status, chirps.published_at, ancestors.depth + 1 FROM chirps
+    JOIN ancestors ON chirps.id = ancestors.in_reply_to
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM ancestors
+ORDER BY depth DESC
+`
+
+type GetChirpAncestorsRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Body        string
+	UserID      uuid.UUID
+	InReplyTo   uuid.NullUUID
+	DeletedAt   sql.NullTime
+	RechirpOf   uuid.NullUUID
+	Status      string
+	PublishedAt sql.NullTime
+}
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, id uuid.UUID) ([]GetChirpAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpAncestorsRow
+	for rows.Next() {
+		var i GetChirpAncestorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpById = `-- name: GetChirpById :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM chirps 
+WHERE id = $1
+`
+
+func (q *Queries) GetChirpById(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpById, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishedAt,
+	)
+	return i, err
+}
+
+const getChirpByIdForUpdate = `-- name: GetChirpByIdForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM chirps WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetChirpByIdForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpByIdForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishedAt,
+	)
+	return i, err
+}
+
+const getChirpDescendantsAfterCursor = `-- name: GetChirpDescendantsAfterCursor :many
+WITH RECURSIVE descendants AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.status, chirps.published_at FROM chirps WHERE chirps.in_reply_to = $5::uuid
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.status, chirps.published_at FROM chirps
+    JOIN descendants ON chirps.in_reply_to = descendants.id
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM descendants
+WHERE (status = 'published' OR user_id = $1::uuid)
+AND ($2::timestamp IS NULL
+    OR (created_at, id) > ($2, $3::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $4
+`
+
+type GetChirpDescendantsAfterCursorParams struct {
+	ViewerID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+	RootID          uuid.UUID
+}
+
+type GetChirpDescendantsAfterCursorRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Body        string
+	UserID      uuid.UUID
+	InReplyTo   uuid.NullUUID
+	DeletedAt   sql.NullTime
+	RechirpOf   uuid.NullUUID
+	Status      string
+	PublishedAt sql.NullTime
+}
+
+func (q *Queries) GetChirpDescendantsAfterCursor(ctx context.Context, arg GetChirpDescendantsAfterCursorParams) ([]GetChirpDescendantsAfterCursorRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendantsAfterCursor,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+		arg.RootID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpDescendantsAfterCursorRow
+	for rows.Next() {
+		var i GetChirpDescendantsAfterCursorRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpDescendantsBeforeCursor = `-- name: GetChirpDescendantsBeforeCursor :many
+WITH RECURSIVE descendants AS (
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.status, chirps.published_at FROM chirps WHERE chirps.in_reply_to = $5::uuid
+    UNION ALL
+    SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.in_reply_to, chirps.deleted_at, chirps.rechirp_of, chirps.status, chirps.published_at FROM chirps
+    JOIN descendants ON chirps.in_reply_to = descendants.id
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM descendants
+WHERE (status = 'published' OR user_id = $1::uuid)
+AND ($2::timestamp IS NULL
+    OR (created_at, id) < ($2, $3::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type GetChirpDescendantsBeforeCursorParams struct {
+	ViewerID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+	RootID          uuid.UUID
+}
+
+type GetChirpDescendantsBeforeCursorRow struct {
+	ID          uuid.UUID
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Body        string
+	UserID      uuid.UUID
+	InReplyTo   uuid.NullUUID
+	DeletedAt   sql.NullTime
+	RechirpOf   uuid.NullUUID
+	Status      string
+	PublishedAt sql.NullTime
+}
+
+func (q *Queries) GetChirpDescendantsBeforeCursor(ctx context.Context, arg GetChirpDescendantsBeforeCursorParams) ([]GetChirpDescendantsBeforeCursorRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendantsBeforeCursor,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+		arg.RootID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpDescendantsBeforeCursorRow
+	for rows.Next() {
+		var i GetChirpDescendantsBeforeCursorRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpEngagement = `-- name: GetChirpEngagement :many
+SELECT chirps.id,
+    (SELECT COUNT(*) FROM chirps replies
+        WHERE replies.in_reply_to = chirps.id AND replies.deleted_at IS NULL
+        AND replies.status = 'published') AS reply_count,
+    (SELECT COUNT(*) FROM likes
+        WHERE likes.chirp_id = chirps.id) AS like_count,
+    (SELECT COUNT(*) FROM chirps rechirps
+        WHERE rechirps.rechirp_of = chirps.id) AS rechirp_count,
+    EXISTS (SELECT 1 FROM likes
+        WHERE likes.chirp_id = chirps.id AND likes.user_id = $1) AS liked_by_viewer,
+    EXISTS (SELECT 1 FROM chirps rechirps
+        WHERE rechirps.rechirp_of = chirps.id AND rechirps.user_id = $1) AS rechirped_by_viewer
+FROM chirps
+WHERE chirps.id = ANY($2::uuid[])
+`
+
+type GetChirpEngagementParams struct {
+	ViewerID uuid.NullUUID
+	ChirpIds []uuid.UUID
+}
+
+type GetChirpEngagementRow struct {
+	ID                uuid.UUID
+	ReplyCount        int64
+	LikeCount         int64
+	RechirpCount      int64
+	LikedByViewer     bool
+	RechirpedByViewer bool
+}
+
+func (q *Queries) GetChirpEngagement(ctx context.Context, arg GetChirpEngagementParams) ([]GetChirpEngagementRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpEngagement, arg.ViewerID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpEngagementRow
+	for rows.Next() {
+		var i GetChirpEngagementRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ReplyCount,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.LikedByViewer,
+			&i.RechirpedByViewer,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsAfterCursor = `-- name: GetChirpsAfterCursor :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM chirps
+WHERE deleted_at IS NULL
+AND (status = 'published' OR user_id = $1)
+AND ($2::uuid IS NULL OR user_id = $2)
+AND ($3::timestamp IS NULL
+    OR (created_at, id) > ($3, $4::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $5
 `
 
-func (q *Queries) GetAllChirpsAsc(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirpsAsc)
+type GetChirpsAfterCursorParams struct {
+	ViewerID        uuid.NullUUID
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetChirpsAfterCursor(ctx context.Context, arg GetChirpsAfterCursorParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsAfterCursor,
+		arg.ViewerID,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -81,6 +512,11 @@ func (q *Queries) GetAllChirpsAsc(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -95,13 +531,33 @@ func (q *Queries) GetAllChirpsAsc(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getAllChirpsDesc = `-- name: GetAllChirpsDesc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
-ORDER BY created_at DESC
+const getChirpsBeforeCursor = `-- name: GetChirpsBeforeCursor :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM chirps
+WHERE deleted_at IS NULL
+AND (status = 'published' OR user_id = $1)
+AND ($2::uuid IS NULL OR user_id = $2)
+AND ($3::timestamp IS NULL
+    OR (created_at, id) < ($3, $4::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $5
 `
 
-func (q *Queries) GetAllChirpsDesc(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirpsDesc)
+type GetChirpsBeforeCursorParams struct {
+	ViewerID        uuid.NullUUID
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetChirpsBeforeCursor(ctx context.Context, arg GetChirpsBeforeCursorParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsBeforeCursor,
+		arg.ViewerID,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +571,11 @@ func (q *Queries) GetAllChirpsDesc(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -129,31 +590,68 @@ func (q *Queries) GetAllChirpsDesc(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getChirpById = `-- name: GetChirpById :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps 
-WHERE id = $1
+const getChirpsByIds = `-- name: GetChirpsByIds :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM chirps
+WHERE id = ANY($1::uuid[])
 `
 
-func (q *Queries) GetChirpById(ctx context.Context, id uuid.UUID) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, getChirpById, id)
-	var i Chirp
-	err := row.Scan(
-		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
-		&i.Body,
-		&i.UserID,
-	)
-	return i, err
+func (q *Queries) GetChirpsByIds(ctx context.Context, chirpIds []uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIds, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
-const getChirpsByUserIdAsc = `-- name: GetChirpsByUserIdAsc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE user_id = $1 ORDER BY created_at ASC
+const getChirpsByStatusAfterCursor = `-- name: GetChirpsByStatusAfterCursor :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM chirps
+WHERE status = $1 AND deleted_at IS NULL
+AND ($2::timestamp IS NULL
+    OR (created_at, id) > ($2, $3::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT $4
 `
 
-func (q *Queries) GetChirpsByUserIdAsc(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByUserIdAsc, userID)
+type GetChirpsByStatusAfterCursorParams struct {
+	Status          string
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetChirpsByStatusAfterCursor(ctx context.Context, arg GetChirpsByStatusAfterCursorParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByStatusAfterCursor,
+		arg.Status,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -167,6 +665,11 @@ func (q *Queries) GetChirpsByUserIdAsc(ctx context.Context, userID uuid.UUID) ([
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -181,13 +684,29 @@ func (q *Queries) GetChirpsByUserIdAsc(ctx context.Context, userID uuid.UUID) ([
 	return items, nil
 }
 
-const getChirpsByUserIdDesc = `-- name: GetChirpsByUserIdDesc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE user_id = $1 ORDER BY created_at DESC
+const getChirpsByStatusBeforeCursor = `-- name: GetChirpsByStatusBeforeCursor :many
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM chirps
+WHERE status = $1 AND deleted_at IS NULL
+AND ($2::timestamp IS NULL
+    OR (created_at, id) < ($2, $3::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
 `
 
-func (q *Queries) GetChirpsByUserIdDesc(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByUserIdDesc, userID)
+type GetChirpsByStatusBeforeCursorParams struct {
+	Status          string
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetChirpsByStatusBeforeCursor(ctx context.Context, arg GetChirpsByStatusBeforeCursorParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByStatusBeforeCursor,
+		arg.Status,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -201,6 +720,11 @@ func (q *Queries) GetChirpsByUserIdDesc(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.InReplyTo,
+			&i.DeletedAt,
+			&i.RechirpOf,
+			&i.Status,
+			&i.PublishedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -214,3 +738,74 @@ func (q *Queries) GetChirpsByUserIdDesc(ctx context.Context, userID uuid.UUID) (
 	}
 	return items, nil
 }
+
+const getRechirp = `-- name: GetRechirp :one
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM chirps
+WHERE user_id = $1 AND rechirp_of = $2::uuid
+`
+
+type GetRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.UUID
+}
+
+func (q *Queries) GetRechirp(ctx context.Context, arg GetRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getRechirp, arg.UserID, arg.RechirpOf)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishedAt,
+	)
+	return i, err
+}
+
+const setChirpStatus = `-- name: SetChirpStatus :one
+UPDATE chirps
+SET status = $1,
+    published_at = COALESCE(published_at, CASE WHEN $1 = 'published' THEN NOW() END),
+    updated_at = NOW()
+WHERE id = $2
+RETURNING id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at
+`
+
+type SetChirpStatusParams struct {
+	Status string
+	ID     uuid.UUID
+}
+
+func (q *Queries) SetChirpStatus(ctx context.Context, arg SetChirpStatusParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, setChirpStatus, arg.Status, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.InReplyTo,
+		&i.DeletedAt,
+		&i.RechirpOf,
+		&i.Status,
+		&i.PublishedAt,
+	)
+	return i, err
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, id)
+	return err
+}
@@ -0,0 +1,123 @@
+package entities
+
+import (
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+type Kind string
+
+const (
+	Hashtag Kind = "hashtag"
+	Mention Kind = "mention"
+)
+
+const (
+	maxHashtagLength = 100
+	maxHandleLength  = 30
+)
+
+// A hashtag or mention found in a chirp body. Start and End are offsets in
+// Unicode code points into the body and cover the leading # or @.
+type Entity struct {
+	Kind  Kind
+	Text  string
+	Start int
+	End   int
+}
+
+var folder = cases.Fold()
+
+// Normalizes a hashtag or handle so that visually identical spellings
+// compare equal: NFKC normalization followed by Unicode case folding
+func Fold(s string) string {
+	return norm.NFKC.String(folder.String(norm.NFKC.String(s)))
+}
+
+// Reports whether s is a usable handle once folded
+func ValidHandle(s string) bool {
+	runes := []rune(s)
+	if len(runes) == 0 || len(runes) > maxHandleLength {
+		return false
+	}
+	for _, c := range runes {
+		if !isWordRune(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// Finds the #hashtags and @mentions in body. The Text of each entity is
+// folded and excludes the sigil.
+func Parse(body string) []Entity {
+	runes := []rune(body)
+	found := []Entity{}
+
+	for i := 0; i < len(runes); i++ {
+		var kind Kind
+		switch runes[i] {
+		case '#', '＃':
+			kind = Hashtag
+		case '@', '＠':
+			kind = Mention
+		default:
+			continue
+		}
+
+		// The sigil must start a word, so "a#b" and "me@example.com" are
+		// not entities
+		if i > 0 && (isWordRune(runes[i-1]) || runes[i-1] == '@' || runes[i-1] == '#') {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+		text := string(runes[i+1 : end])
+		if !validEntity(kind, []rune(text)) {
+			i = end - 1
+			continue
+		}
+
+		found = append(found, Entity{
+			Kind:  kind,
+			Text:  Fold(text),
+			Start: i,
+			End:   end,
+		})
+		i = end - 1
+	}
+
+	return found
+}
+
+func validEntity(kind Kind, text []rune) bool {
+	if len(text) == 0 {
+		return false
+	}
+
+	switch kind {
+	case Hashtag:
+		if len(text) > maxHashtagLength {
+			return false
+		}
+		// A run of digits like #1 is not a hashtag
+		for _, c := range text {
+			if !unicode.IsDigit(c) {
+				return true
+			}
+		}
+		return false
+	case Mention:
+		return len(text) <= maxHandleLength
+	}
+	return false
+}
+
+func isWordRune(c rune) bool {
+	return c == '_' || unicode.IsLetter(c) || unicode.IsDigit(c) || unicode.Is(unicode.Mn, c)
+}
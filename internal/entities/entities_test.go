@@ -0,0 +1,121 @@
+package entities
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"Go", "go"},
+		{"GoLang", "golang"},
+		{"ＧＯ", "go"},
+		{"Straße", "strasse"},
+		{"café", "café"},
+		{"cafe\u0301", "café"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Fold(tt.s); got != tt.want {
+			t.Errorf("Fold(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestValidHandle(t *testing.T) {
+	tests := []struct {
+		s    string
+		want bool
+	}{
+		{"chirpy_fan", true},
+		{"ünïcödé", true},
+		{"a", true},
+		{strings.Repeat("a", 30), true},
+		{strings.Repeat("a", 31), false},
+		{"", false},
+		{"with space", false},
+		{"dash-ed", false},
+		{"@handle", false},
+	}
+	for _, tt := range tests {
+		if got := ValidHandle(tt.s); got != tt.want {
+			t.Errorf("ValidHandle(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []Entity
+	}{
+		{
+			name: "none",
+			body: "just a chirp",
+			want: []Entity{},
+		},
+		{
+			name: "hashtag and mention",
+			body: "Hello @Alice, welcome to #Chirpy!",
+			want: []Entity{
+				{Kind: Mention, Text: "alice", Start: 6, End: 12},
+				{Kind: Hashtag, Text: "chirpy", Start: 25, End: 32},
+			},
+		},
+		{
+			name: "offsets count code points",
+			body: "héllo #wörld",
+			want: []Entity{{Kind: Hashtag, Text: "wörld", Start: 6, End: 12}},
+		},
+		{
+			name: "full width sigils",
+			body: "＃Go ＠bob",
+			want: []Entity{
+				{Kind: Hashtag, Text: "go", Start: 0, End: 3},
+				{Kind: Mention, Text: "bob", Start: 4, End: 8},
+			},
+		},
+		{
+			name: "sigil inside a word",
+			body: "a#b me@example.com",
+			want: []Entity{},
+		},
+		{
+			name: "doubled sigils",
+			body: "##tag @@user",
+			want: []Entity{},
+		},
+		{
+			name: "digits only",
+			body: "#1 #2024 #go2",
+			want: []Entity{{Kind: Hashtag, Text: "go2", Start: 9, End: 13}},
+		},
+		{
+			name: "bare sigils",
+			body: "# @ #!",
+			want: []Entity{},
+		},
+		{
+			name: "mention too long",
+			body: "@" + strings.Repeat("a", 31),
+			want: []Entity{},
+		},
+		{
+			name: "underscores",
+			body: "#snake_case",
+			want: []Entity{{Kind: Hashtag, Text: "snake_case", Start: 0, End: 11}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.body); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.body, got, tt.want)
+			}
+		})
+	}
+}
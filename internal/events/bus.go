@@ -0,0 +1,163 @@
+package events
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Handles an event for a subscriber. An error stops the subscriber at
+// that event, which is handed to it again on the next pass, so handlers
+// must cope with seeing an event more than once.
+type Handler func(ctx context.Context, event Envelope) error
+
+const (
+	DefaultBatchSize = 100
+	// Handled events are kept this long, for subscribers that fell behind
+	retention = 7 * 24 * time.Hour
+)
+
+type subscriber struct {
+	name    string
+	handler Handler
+}
+
+// Hands outbox events to subscribers, remembering how far each got in
+// event_checkpoints. Every instance can run a bus; each subscriber is
+// only served by one of them at a time.
+type Bus struct {
+	DB        *sql.DB
+	Queries   *database.Queries
+	BatchSize int32
+
+	subscribers []subscriber
+}
+
+func NewBus(db *sql.DB, queries *database.Queries) *Bus {
+	return &Bus{DB: db, Queries: queries, BatchSize: DefaultBatchSize}
+}
+
+// Registers a handler under a name that must not change between
+// releases, since the checkpoint is stored by name. A new subscriber
+// starts with the oldest event still in the outbox.
+func (b *Bus) Subscribe(name string, handler Handler) {
+	b.subscribers = append(b.subscribers, subscriber{name: name, handler: handler})
+}
+
+// Hands every subscriber its next batch of events, reporting whether any
+// subscriber filled a batch and so may have more waiting
+func (b *Bus) Dispatch(ctx context.Context) (bool, error) {
+	more := false
+	var errs []error
+	for _, s := range b.subscribers {
+		n, err := b.dispatch(ctx, s)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+		}
+		more = more || n == int(b.BatchSize)
+	}
+	return more, errors.Join(errs...)
+}
+
+func (b *Bus) dispatch(ctx context.Context, s subscriber) (int, error) {
+	err := b.Queries.CreateEventCheckpoint(ctx, s.name)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := b.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	queries := b.Queries.WithTx(tx)
+
+	// Another instance is serving the subscriber
+	locked, err := queries.TryLockEventSubscriber(ctx, s.name)
+	if err != nil || !locked {
+		return 0, err
+	}
+
+	checkpoint, err := queries.GetEventCheckpoint(ctx, s.name)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := queries.GetOutboxEventsAfter(ctx, database.GetOutboxEventsAfterParams{
+		TransactionID: checkpoint.TransactionID,
+		Seq:           checkpoint.Seq,
+		PageLimit:     b.BatchSize,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Events up to a failed one are checkpointed, the rest wait for the
+	// next pass
+	handled := 0
+	for _, row := range rows {
+		envelope, err := decode(row)
+		if err == nil {
+			err = s.handler(ctx, envelope)
+		}
+		if err != nil {
+			log.Printf("events: %s failed on %s event %s: %v", s.name, row.Type, row.ID, err)
+			break
+		}
+		handled++
+	}
+	if handled == 0 {
+		return 0, nil
+	}
+
+	last := rows[handled-1]
+	err = queries.SaveEventCheckpoint(ctx, database.SaveEventCheckpointParams{
+		Subscriber:    s.name,
+		TransactionID: last.TransactionID,
+		Seq:           last.Seq,
+	})
+	if err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return handled, nil
+}
+
+// Dispatches events every interval until ctx is done, straight away
+// again while subscribers have events waiting. Events handled by every
+// subscriber are pruned once they are older than a week.
+func (b *Bus) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	lastPrune := time.Time{}
+
+	for {
+		more, err := b.Dispatch(ctx)
+		if err != nil {
+			log.Printf("events: dispatching: %v", err)
+		}
+
+		if time.Since(lastPrune) > time.Hour {
+			lastPrune = time.Now()
+			if _, err := b.Queries.DeleteHandledOutboxEvents(ctx, time.Now().Add(-retention)); err != nil {
+				log.Printf("events: pruning outbox: %v", err)
+			}
+		}
+
+		if more {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
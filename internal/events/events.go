@@ -0,0 +1,215 @@
+// Package events records Chirpy's domain events in an outbox table, in
+// the same transaction as the change they describe, and hands them to
+// in-process subscribers. Subscribers see every committed event at least
+// once, in commit order, and never an event whose change was rolled back.
+package events
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Event interface {
+	Type() string
+}
+
+const (
+	TypeChirpCreated   = "chirp.created"
+	TypeChirpDeleted   = "chirp.deleted"
+	TypeChirpRestored  = "chirp.restored"
+	TypeUserCreated    = "user.created"
+	TypeUserUpgraded   = "user.upgraded"
+	TypeChirpLiked     = "chirp.liked"
+	TypeChirpRechirped = "chirp.rechirped"
+)
+
+// A chirp was published. Held chirps are not announced.
+type ChirpCreated struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Body      string     `json:"body"`
+	InReplyTo *uuid.UUID `json:"in_reply_to,omitempty"`
+	// Folded, without the #
+	Hashtags []string `json:"hashtags"`
+}
+
+// A chirp hidden by moderators was published again. It was announced as
+// created before, under the same ID.
+type ChirpRestored ChirpCreated
+
+type ChirpDeleted struct {
+	ID       uuid.UUID `json:"id"`
+	UserID   uuid.UUID `json:"user_id"`
+	Hashtags []string  `json:"hashtags"`
+}
+
+// Email addresses are left out, events travel to other services
+type UserCreated struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Handle    *string   `json:"handle"`
+}
+
+// The user started a Chirpy Red subscription
+type UserUpgraded struct {
+	UserID           uuid.UUID `json:"user_id"`
+	Plan             string    `json:"plan"`
+	CurrentPeriodEnd time.Time `json:"current_period_end"`
+}
+
+// UserID liked AuthorID's chirp
+type ChirpLiked struct {
+	ChirpID  uuid.UUID `json:"chirp_id"`
+	AuthorID uuid.UUID `json:"author_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+// UserID rechirped AuthorID's chirp as the chirp ID
+type ChirpRechirped struct {
+	ID       uuid.UUID `json:"id"`
+	ChirpID  uuid.UUID `json:"chirp_id"`
+	AuthorID uuid.UUID `json:"author_id"`
+	UserID   uuid.UUID `json:"user_id"`
+}
+
+func (ChirpCreated) Type() string   { return TypeChirpCreated }
+func (ChirpDeleted) Type() string   { return TypeChirpDeleted }
+func (ChirpRestored) Type() string  { return TypeChirpRestored }
+func (UserCreated) Type() string    { return TypeUserCreated }
+func (UserUpgraded) Type() string   { return TypeUserUpgraded }
+func (ChirpLiked) Type() string     { return TypeChirpLiked }
+func (ChirpRechirped) Type() string { return TypeChirpRechirped }
+
+// Writes the event to the outbox. Pass the queries of the transaction
+// making the change, so the event is only published if it commits.
+func Publish(ctx context.Context, queries *database.Queries, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return queries.InsertOutboxEvent(ctx, database.InsertOutboxEventParams{
+		ID:      uuid.New(),
+		Type:    event.Type(),
+		Payload: payload,
+	})
+}
+
+// Where an event sits in the outbox. Events are read in position order.
+type Position struct {
+	TransactionID int64
+	Seq           int64
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d-%d", p.TransactionID, p.Seq)
+}
+
+func (p Position) After(o Position) bool {
+	if p.TransactionID != o.TransactionID {
+		return p.TransactionID > o.TransactionID
+	}
+	return p.Seq > o.Seq
+}
+
+func ParsePosition(s string) (Position, error) {
+	var p Position
+	_, err := fmt.Sscanf(s, "%d-%d", &p.TransactionID, &p.Seq)
+	if err != nil {
+		return Position{}, fmt.Errorf("invalid event position %q", s)
+	}
+	return p, nil
+}
+
+// An event read back from the outbox
+type Envelope struct {
+	ID        uuid.UUID
+	Position  Position
+	CreatedAt time.Time
+	Type      string
+	Payload   json.RawMessage
+	// Nil for types this version does not know
+	Event Event
+}
+
+func decode(row database.OutboxEvent) (Envelope, error) {
+	envelope := Envelope{
+		ID:        row.ID,
+		Position:  Position{TransactionID: row.TransactionID, Seq: row.Seq},
+		CreatedAt: row.CreatedAt,
+		Type:      row.Type,
+		Payload:   row.Payload,
+	}
+
+	var event Event
+	var err error
+	switch row.Type {
+	case TypeChirpCreated:
+		event, err = unmarshal[ChirpCreated](row.Payload)
+	case TypeChirpDeleted:
+		event, err = unmarshal[ChirpDeleted](row.Payload)
+	case TypeChirpRestored:
+		event, err = unmarshal[ChirpRestored](row.Payload)
+	case TypeUserCreated:
+		event, err = unmarshal[UserCreated](row.Payload)
+	case TypeUserUpgraded:
+		event, err = unmarshal[UserUpgraded](row.Payload)
+	case TypeChirpLiked:
+		event, err = unmarshal[ChirpLiked](row.Payload)
+	case TypeChirpRechirped:
+		event, err = unmarshal[ChirpRechirped](row.Payload)
+	}
+	if err != nil {
+		return envelope, fmt.Errorf("decoding %s event %s: %w", row.Type, row.ID, err)
+	}
+	envelope.Event = event
+	return envelope, nil
+}
+
+// Reads up to limit events committed after the position
+func Read(ctx context.Context, queries *database.Queries, after Position, limit int32) ([]Envelope, error) {
+	rows, err := queries.GetOutboxEventsAfter(ctx, database.GetOutboxEventsAfterParams{
+		TransactionID: after.TransactionID,
+		Seq:           after.Seq,
+		PageLimit:     limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	envelopes := []Envelope{}
+	for _, row := range rows {
+		envelope, err := decode(row)
+		if err != nil {
+			return nil, err
+		}
+		envelopes = append(envelopes, envelope)
+	}
+	return envelopes, nil
+}
+
+// The position of the newest event readers can see, so they can start
+// from there rather than the beginning
+func Latest(ctx context.Context, queries *database.Queries) (Position, error) {
+	row, err := queries.GetLatestOutboxPosition(ctx)
+	if err == sql.ErrNoRows {
+		return Position{}, nil
+	}
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{TransactionID: row.TransactionID, Seq: row.Seq}, nil
+}
+
+func unmarshal[E Event](payload json.RawMessage) (Event, error) {
+	var event E
+	err := json.Unmarshal(payload, &event)
+	return event, err
+}
@@ -0,0 +1,146 @@
+package events
+
+import (
+	"chirpy/internal/database"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPositionString(t *testing.T) {
+	tests := []struct {
+		position Position
+		want     string
+	}{
+		{Position{}, "0-0"},
+		{Position{TransactionID: 1234, Seq: 56}, "1234-56"},
+	}
+	for _, tt := range tests {
+		if got := tt.position.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.position, got, tt.want)
+		}
+		if parsed, err := ParsePosition(tt.want); err != nil || parsed != tt.position {
+			t.Errorf("ParsePosition(%q) = %+v, %v, want %+v", tt.want, parsed, err, tt.position)
+		}
+	}
+}
+
+func TestParsePosition(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Position
+		wantErr bool
+	}{
+		{"10-2", Position{TransactionID: 10, Seq: 2}, false},
+		{"", Position{}, true},
+		{"10", Position{}, true},
+		{"10-", Position{}, true},
+		{"-", Position{}, true},
+		{"abc-def", Position{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParsePosition(tt.s)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParsePosition(%q) = %+v, %v, want %+v, error %v", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestPositionAfter(t *testing.T) {
+	tests := []struct {
+		p, o Position
+		want bool
+	}{
+		{Position{10, 1}, Position{9, 5}, true},
+		{Position{9, 5}, Position{10, 1}, false},
+		{Position{10, 2}, Position{10, 1}, true},
+		{Position{10, 1}, Position{10, 2}, false},
+		{Position{10, 1}, Position{10, 1}, false},
+		{Position{1, 0}, Position{}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.p.After(tt.o); got != tt.want {
+			t.Errorf("%s.After(%s) = %v, want %v", tt.p, tt.o, got, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	chirpID, userID := uuid.New(), uuid.New()
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		event   Event
+		typ     string
+		payload string
+		wantErr bool
+	}{
+		{
+			name:  "chirp created",
+			event: ChirpCreated{ID: chirpID, CreatedAt: createdAt, UserID: userID, Body: "hi #go", Hashtags: []string{"go"}},
+		},
+		{
+			name:  "chirp deleted",
+			event: ChirpDeleted{ID: chirpID, UserID: userID, Hashtags: []string{"go"}},
+		},
+		{
+			name:  "chirp restored",
+			event: ChirpRestored{ID: chirpID, CreatedAt: createdAt, UserID: userID, Body: "hi #go", Hashtags: []string{"go"}},
+		},
+		{
+			name:  "chirp liked",
+			event: ChirpLiked{ChirpID: chirpID, UserID: userID},
+		},
+		{
+			name:    "unknown type",
+			typ:     "chirp.teleported",
+			payload: `{"id": "` + chirpID.String() + `"}`,
+		},
+		{
+			name:    "invalid payload",
+			typ:     TypeChirpDeleted,
+			payload: `{"id": 5}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typ, payload := tt.typ, []byte(tt.payload)
+			if tt.event != nil {
+				var err error
+				typ = tt.event.Type()
+				payload, err = json.Marshal(tt.event)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			row := database.OutboxEvent{
+				ID:            uuid.New(),
+				CreatedAt:     createdAt,
+				Type:          typ,
+				Payload:       payload,
+				TransactionID: 10,
+				Seq:           2,
+			}
+			envelope, err := decode(row)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decode() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if envelope.ID != row.ID || envelope.Type != typ || envelope.Position != (Position{10, 2}) || string(envelope.Payload) != string(payload) {
+				t.Errorf("decode() = %+v, does not match the row %+v", envelope, row)
+			}
+			if !reflect.DeepEqual(envelope.Event, tt.event) {
+				t.Errorf("Event = %#v, want %#v", envelope.Event, tt.event)
+			}
+		})
+	}
+}
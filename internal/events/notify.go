@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Postgres channel notified when a transaction that wrote to the outbox
+// commits
+const NotifyChannel = "outbox_events"
+
+// Listens for outbox notifications on a connection of its own until ctx
+// is done. The returned channel is signalled after commits, and after
+// reconnecting since notifications may have been missed meanwhile.
+func Listen(ctx context.Context, dbURL string) (<-chan struct{}, error) {
+	listener := pq.NewListener(dbURL, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("events: listener: %v", err)
+		}
+	})
+	if err := listener.Listen(NotifyChannel); err != nil {
+		listener.Close()
+		return nil, err
+	}
+
+	wake := make(chan struct{}, 1)
+	go func() {
+		defer listener.Close()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			// nil after a reconnect
+			case <-listener.Notify:
+			}
+			select {
+			case wake <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	return wake, nil
+}
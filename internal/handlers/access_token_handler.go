@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/util"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Personal access tokens can only be managed with a JWT, so a leaked
+// token cannot be used to mint more
+func AccessTokenRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("POST /api/tokens", http.HandlerFunc(apiConfig.createAccessToken))
+	s.Handle("GET /api/tokens", http.HandlerFunc(apiConfig.getAccessTokens))
+	s.Handle("DELETE /api/tokens/{tokenID}", http.HandlerFunc(apiConfig.revokeAccessToken))
+}
+
+type AccessToken struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	// Only returned when the token is created
+	Token string `json:"token,omitempty"`
+}
+
+func accessTokenResponse(token database.PersonalAccessToken) AccessToken {
+	return AccessToken{
+		ID:         token.ID,
+		CreatedAt:  token.CreatedAt,
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     token.Scopes,
+		ExpiresAt:  nullableTime(token.ExpiresAt),
+		LastUsedAt: nullableTime(token.LastUsedAt),
+	}
+}
+
+func (cfg *ApiConfig) createAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	type createTokenRequest struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+		// Never expires when left out
+		ExpiresInDays int `json:"expires_in_days"`
+	}
+	params, err := util.DecodeJSON[createTokenRequest](r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	if params.Name == "" || len(params.Name) > 100 {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "name must be 1-100 characters"})
+		return
+	}
+
+	err = auth.ValidateScopes(params.Scopes)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, struct {
+			Error  string   `json:"error"`
+			Scopes []string `json:"scopes"`
+		}{Error: err.Error(), Scopes: auth.Scopes})
+		return
+	}
+
+	if params.ExpiresInDays < 0 {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "expires_in_days must be positive"})
+		return
+	}
+	expiresAt := sql.NullTime{}
+	if params.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, params.ExpiresInDays), Valid: true}
+	}
+
+	token, prefix, hash, err := auth.MakePersonalAccessToken()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	created, err := cfg.DbQueries.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		UserID:    userID,
+		Name:      params.Name,
+		Prefix:    prefix,
+		TokenHash: hash,
+		Scopes:    params.Scopes,
+		ExpiresAt: expiresAt,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	response := accessTokenResponse(created)
+	response.Token = token
+	util.RespondWithJSON(w, http.StatusCreated, response)
+}
+
+func (cfg *ApiConfig) getAccessTokens(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	tokens, err := cfg.DbQueries.GetUserPersonalAccessTokens(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	response := []AccessToken{}
+	for _, token := range tokens {
+		response = append(response, accessTokenResponse(token))
+	}
+
+	util.RespondWithJSON(w, 200, response)
+}
+
+func (cfg *ApiConfig) revokeAccessToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid token id"})
+		return
+	}
+
+	revoked, err := cfg.DbQueries.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{
+		ID:     tokenID,
+		UserID: userID,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if revoked == 0 {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Token not found"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
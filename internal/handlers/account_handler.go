@@ -0,0 +1,248 @@
+package handlers
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/mailer"
+	"chirpy/util"
+	"context"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+func AccountRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("POST /api/password/forgot", http.HandlerFunc(apiConfig.forgotPassword))
+	s.Handle("POST /api/password/reset", http.HandlerFunc(apiConfig.resetPassword))
+	s.Handle("GET /reset-password", http.HandlerFunc(resetPasswordPage))
+	s.Handle("GET /api/users/verify", http.HandlerFunc(apiConfig.verifyEmail))
+	s.Handle("POST /api/users/verify/resend", http.HandlerFunc(apiConfig.resendVerification))
+}
+
+const (
+	passwordResetLifetime = time.Hour
+	verifyEmailLifetime   = 48 * time.Hour
+)
+
+// Sends in the background so response times do not reveal whether an
+// email address has an account
+func (cfg *ApiConfig) sendMail(msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := cfg.Mailer.Send(ctx, msg); err != nil {
+			log.Printf("mailer: sending %q failed: %v", msg.Subject, err)
+		}
+	}()
+}
+
+func (cfg *ApiConfig) appLink(path string, token string) string {
+	return cfg.AppURL + path + "?" + url.Values{"token": {token}}.Encode()
+}
+
+func (cfg *ApiConfig) sendVerificationEmail(user database.User) error {
+	token, err := auth.MakePurposeJWT(user.ID, auth.PurposeVerifyEmail, user.Email, cfg.Keys, verifyEmailLifetime)
+	if err != nil {
+		return err
+	}
+
+	cfg.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your Chirpy email address",
+		Body: "Confirm this is your email address by opening the link below:\n\n" +
+			cfg.appLink("/api/users/verify", token) + "\n\n" +
+			"The link expires in 48 hours.\n",
+	})
+	return nil
+}
+
+// Spends a single use token, reporting false if it was already used
+func useToken(ctx context.Context, queries *database.Queries, token auth.PurposeToken) (bool, error) {
+	err := queries.DeleteExpiredUsedTokens(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	used, err := queries.UseToken(ctx, database.UseTokenParams{
+		ID:        token.ID,
+		ExpiresAt: token.ExpiresAt,
+	})
+	return used == 1, err
+}
+
+// Always answers 202 so the endpoint cannot be used to find accounts
+func (cfg *ApiConfig) forgotPassword(w http.ResponseWriter, r *http.Request) {
+	type forgotRequest struct {
+		Email string `json:"email"`
+	}
+	params, err := util.DecodeJSON[forgotRequest](r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserByEmail(r.Context(), params.Email)
+	if err == nil {
+		token, err := auth.MakePurposeJWT(user.ID, auth.PurposePasswordReset, user.Email, cfg.Keys, passwordResetLifetime)
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+		cfg.sendMail(mailer.Message{
+			To:      user.Email,
+			Subject: "Reset your Chirpy password",
+			Body: "Someone asked to reset the password of your Chirpy account. " +
+				"If it was you, open the link below within the hour:\n\n" +
+				cfg.appLink("/reset-password", token) + "\n\n" +
+				"If it wasn't, you can ignore this email.\n",
+		})
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Opened from the link in the reset email. The page posts the token and
+// the new password to POST /api/password/reset; the token is only checked
+// there.
+func resetPasswordPage(w http.ResponseWriter, r *http.Request) {
+	tmpl, err := template.ParseFiles("./account/reset_password.html")
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Cache-Control", "no-store")
+	// Keeps the token in the URL out of the Referer of anything it loads
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	tmpl.Execute(w, struct{ Token string }{Token: r.URL.Query().Get("token")})
+}
+
+// Sets a new password and signs every session out
+func (cfg *ApiConfig) resetPassword(w http.ResponseWriter, r *http.Request) {
+	type resetRequest struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	params, err := util.DecodeJSON[resetRequest](r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	token, err := auth.ValidatePurposeJWT(params.Token, auth.PurposePasswordReset, cfg.Keys)
+	if err != nil {
+		util.RespondWithError(w, http.StatusUnauthorized, util.ResponseError{Error: "invalid or expired token"})
+		return
+	}
+
+	err = auth.CheckPasswordStrength(params.Password, token.Email)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(params.Password)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
+
+	ok, err := useToken(r.Context(), queries, token)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if !ok {
+		util.RespondWithError(w, http.StatusUnauthorized, util.ResponseError{Error: "token has already been used"})
+		return
+	}
+
+	err = queries.UpdatePassword(r.Context(), database.UpdatePasswordParams{
+		HashedPassword: hashedPassword,
+		ID:             token.UserID,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = queries.RevokeUserRefreshTokens(r.Context(), token.UserID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = tx.Commit()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Opened from the link in the verification email
+func (cfg *ApiConfig) verifyEmail(w http.ResponseWriter, r *http.Request) {
+	token, err := auth.ValidatePurposeJWT(r.URL.Query().Get("token"), auth.PurposeVerifyEmail, cfg.Keys)
+	if err != nil {
+		util.RespondWithError(w, http.StatusUnauthorized, util.ResponseError{Error: "invalid or expired token"})
+		return
+	}
+
+	ok, err := useToken(r.Context(), cfg.DbQueries, token)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if !ok {
+		util.RespondWithError(w, http.StatusUnauthorized, util.ResponseError{Error: "token has already been used"})
+		return
+	}
+
+	// Only verifies the address the link was sent to, in case the email
+	// was changed in the meantime
+	verified, err := cfg.DbQueries.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{
+		ID:    token.UserID,
+		Email: token.Email,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if verified == 0 {
+		util.RespondWithError(w, http.StatusConflict, util.ResponseError{Error: "email address has changed since the link was sent"})
+		return
+	}
+
+	util.RespondWithJSON(w, 200, struct {
+		Email    string `json:"email"`
+		Verified bool   `json:"verified"`
+	}{Email: token.Email, Verified: true})
+}
+
+func (cfg *ApiConfig) resendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserById(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		util.RespondWithError(w, http.StatusConflict, util.ResponseError{Error: "email address is already verified"})
+		return
+	}
+
+	err = cfg.sendVerificationEmail(user)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -1,15 +1,22 @@
 package handlers
 
 import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
 	"chirpy/util"
+	"database/sql"
 	"net/http"
 	"os"
 	"text/template"
+
+	"github.com/google/uuid"
 )
 
 func AdminRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
-	s.Handle("GET /admin/metrics", http.HandlerFunc(apiConfig.printMetric))
-	s.Handle("POST /admin/reset", http.HandlerFunc(apiConfig.resetMetric))
+	s.Handle("GET /admin/metrics", apiConfig.requireRole(auth.RoleAdmin, apiConfig.printMetric))
+	s.Handle("POST /admin/reset", apiConfig.requireRole(auth.RoleAdmin, apiConfig.resetMetric))
+	s.Handle("PUT /admin/users/{userID}/role", apiConfig.requireRole(auth.RoleAdmin, apiConfig.setUserRole))
+	s.Handle("DELETE /admin/users/{userID}/lockout", apiConfig.requireRole(auth.RoleAdmin, apiConfig.unlockUser))
 }
 
 type MetricPageData struct {
@@ -36,3 +43,69 @@ func (cfg *ApiConfig) resetMetric(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+func (cfg *ApiConfig) setUserRole(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid user id"})
+		return
+	}
+
+	type roleRequest struct {
+		Role string `json:"role"`
+	}
+	params, err := util.DecodeJSON[roleRequest](r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	role, err := auth.ParseRole(params.Role)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	user, err := cfg.DbQueries.SetUserRole(r.Context(), database.SetUserRoleParams{
+		Role: string(role),
+		ID:   userID,
+	})
+	if err == sql.ErrNoRows {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "User not found"})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	util.RespondWithJSON(w, 200, struct {
+		ID   uuid.UUID `json:"id"`
+		Role string    `json:"role"`
+	}{ID: user.ID, Role: user.Role})
+}
+
+// Clears the failed logins of an account so its owner can sign in again
+// straight away. Failures counted against IPs are left alone.
+func (cfg *ApiConfig) unlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid user id"})
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserById(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "User not found"})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = cfg.LoginThrottle.Succeed(r.Context(), user.Email)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
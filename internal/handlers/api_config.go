@@ -1,13 +1,35 @@
 package handlers
 
 import (
+	"chirpy/internal/auth"
 	"chirpy/internal/database"
+	"chirpy/internal/mailer"
+	"chirpy/internal/moderation"
+	"chirpy/internal/ratelimit"
+	"chirpy/internal/stream"
+	"database/sql"
 	"sync/atomic"
 )
 
 type ApiConfig struct {
 	FileserverHits atomic.Int32
+	DB             *sql.DB
 	DbQueries      *database.Queries
-	JwtSecret      string
-	PolkaKey       string
+	Moderator      *moderation.Moderator
+	Keys           *auth.KeySet
+	// Polka signs its webhooks with this secret
+	PolkaWebhookSecret string
+	Mailer             mailer.Mailer
+	// Base URL of the links in emails
+	AppURL string
+	// Stops users posting until their email address is verified
+	RequireVerifiedEmail bool
+	LoginThrottle        *LoginThrottle
+	// Requests are not limited when RateLimiter is nil
+	RateLimiter *ratelimit.Limiter
+	RateLimits  RateLimits
+	// Fans chirp events out to the clients of /api/stream
+	Stream *stream.Hub
+
+	memberships membershipCache
 }
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/util"
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Validates the request's bearer JWT and checks that the session it was
+// minted from has not been signed out
+func (cfg *ApiConfig) accessToken(r *http.Request) (auth.AccessToken, error) {
+	tokenString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return auth.AccessToken{}, err
+	}
+
+	return cfg.validateAccessToken(r.Context(), tokenString)
+}
+
+func (cfg *ApiConfig) validateAccessToken(ctx context.Context, tokenString string) (auth.AccessToken, error) {
+	token, err := auth.ParseJWT(tokenString, cfg.Keys)
+	if err != nil {
+		return auth.AccessToken{}, err
+	}
+
+	if token.SessionID.Valid {
+		active, err := cfg.DbQueries.SessionActive(ctx, token.SessionID.UUID)
+		if err != nil {
+			return auth.AccessToken{}, err
+		}
+		if !active {
+			return auth.AccessToken{}, errors.New("session has been revoked")
+		}
+	}
+
+	return token, nil
+}
+
+// Like accessToken, but refuses tokens issued to OAuth clients, which are
+// only accepted where a scope covers the endpoint, and suspended users
+func (cfg *ApiConfig) userToken(r *http.Request) (auth.AccessToken, error) {
+	token, err := cfg.accessToken(r)
+	if err != nil {
+		return auth.AccessToken{}, err
+	}
+	if token.ClientID.Valid {
+		return auth.AccessToken{}, errors.New("not available to OAuth clients")
+	}
+	if err := cfg.checkNotSuspended(r.Context(), token.UserID); err != nil {
+		return auth.AccessToken{}, err
+	}
+	return token, nil
+}
+
+// Returns the ID of the user the request's bearer JWT was issued to
+func (cfg *ApiConfig) authenticate(r *http.Request) (uuid.UUID, error) {
+	token, err := cfg.userToken(r)
+	return token.UserID, err
+}
+
+var (
+	errInsufficientScope = errors.New("token is missing the required scope")
+	errAccountSuspended  = errors.New("account suspended")
+)
+
+// Tokens stay valid after their user is suspended, so every
+// authentication looks the user up again
+func (cfg *ApiConfig) checkNotSuspended(ctx context.Context, userID uuid.UUID) error {
+	user, err := cfg.DbQueries.GetUserById(ctx, userID)
+	if err == sql.ErrNoRows {
+		return errors.New("User not found")
+	}
+	if err != nil {
+		return err
+	}
+	if user.SuspendedAt.Valid {
+		return errAccountSuspended
+	}
+	return nil
+}
+
+// Like authenticate, but also accepts personal access tokens and OAuth
+// client tokens that carry the scope. Users' own JWTs can do anything
+// their user can.
+func (cfg *ApiConfig) authenticateScope(r *http.Request, scope string) (uuid.UUID, error) {
+	tokenString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if !auth.IsPersonalAccessToken(tokenString) {
+		token, err := cfg.accessToken(r)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if token.ClientID.Valid && !slices.Contains(token.Scopes, scope) {
+			return uuid.Nil, errInsufficientScope
+		}
+		return token.UserID, cfg.checkNotSuspended(r.Context(), token.UserID)
+	}
+
+	token, err := cfg.DbQueries.GetPersonalAccessTokenByHash(r.Context(), auth.HashAccessToken(tokenString))
+	if err == sql.ErrNoRows || (err == nil && token.RevokedAt.Valid) {
+		return uuid.Nil, errors.New("invalid access token")
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if token.ExpiresAt.Valid && time.Now().After(token.ExpiresAt.Time) {
+		return uuid.Nil, errors.New("access token has expired")
+	}
+	if !slices.Contains(token.Scopes, scope) {
+		return uuid.Nil, errInsufficientScope
+	}
+
+	err = cfg.DbQueries.TouchPersonalAccessToken(r.Context(), token.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return token.UserID, cfg.checkNotSuspended(r.Context(), token.UserID)
+}
+
+// Responds 403 when a token lacks a scope or its user is suspended and
+// 401 for any other failure
+func respondAuthError(w http.ResponseWriter, err error) {
+	code := http.StatusUnauthorized
+	if err == errInsufficientScope || err == errAccountSuspended {
+		code = http.StatusForbidden
+	}
+	util.RespondWithError(w, code, util.ResponseError{Error: err.Error()})
+}
+
+// Returns the caller on endpoints that are public but personalise their
+// response for signed in users; missing or invalid tokens yield no viewer
+func (cfg *ApiConfig) viewer(r *http.Request) uuid.NullUUID {
+	userID, err := cfg.authenticateScope(r, auth.ScopeChirpsRead)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+
+	return uuid.NullUUID{UUID: userID, Valid: true}
+}
+
+// Wraps a handler so it only runs for callers whose token carries at
+// least the required role
+func (cfg *ApiConfig) requireRole(required auth.Role, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := cfg.userToken(r)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+
+		if !token.Role.Allows(required) {
+			util.RespondWithError(w, http.StatusForbidden, util.ResponseError{Error: string(required) + " role required"})
+			return
+		}
+
+		next(w, r)
+	})
+}
+
+// Loads the user and rejects suspended accounts with a 403
+func (cfg *ApiConfig) activeUser(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.User, bool) {
+	user, err := cfg.DbQueries.GetUserById(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		util.RespondWithError(w, http.StatusUnauthorized, util.ResponseError{Error: "User not found"})
+		return user, false
+	}
+	if util.ErrorNotNil(err, w) {
+		return user, false
+	}
+	if user.SuspendedAt.Valid {
+		util.RespondWithError(w, http.StatusForbidden, util.ResponseError{Error: "account suspended"})
+		return user, false
+	}
+	return user, true
+}
@@ -3,10 +3,13 @@ package handlers
 import (
 	"chirpy/internal/auth"
 	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"chirpy/internal/events"
+	"chirpy/internal/moderation"
 	"chirpy/util"
+	"context"
 	"database/sql"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,6 +17,7 @@ import (
 
 func ChirpRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
 	s.Handle("GET /api/chirps", http.HandlerFunc(apiConfig.getAllChirps))
+	s.Handle("GET /api/chirps/search", http.HandlerFunc(apiConfig.searchChirps))
 	s.Handle("GET /api/chirps/{chirpID}", http.HandlerFunc(apiConfig.getChirp))
 	s.Handle("POST /api/chirps", http.HandlerFunc(apiConfig.addChirp))
 	s.Handle("DELETE /api/chirps/{chirpID}", http.HandlerFunc(apiConfig.deleteChirp))
@@ -21,34 +25,78 @@ func ChirpRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
 }
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID            uuid.UUID  `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Body          string     `json:"body"`
+	UserID        uuid.UUID  `json:"user_id"`
+	InReplyTo     *uuid.UUID `json:"in_reply_to"`
+	RechirpOf     *Chirp     `json:"rechirp_of,omitempty"`
+	ReplyCount    int64      `json:"reply_count"`
+	LikeCount     int64      `json:"like_count"`
+	RechirpCount  int64      `json:"rechirp_count"`
+	LikedByMe     *bool      `json:"liked_by_me,omitempty"`
+	RechirpedByMe *bool      `json:"rechirped_by_me,omitempty"`
+	Deleted       bool       `json:"deleted,omitempty"`
+	Status        string     `json:"status"`
+	Entities      []Entity   `json:"entities"`
+}
+
+const (
+	chirpStatusPublished = "published"
+	chirpStatusHeld      = "held"
+	chirpStatusHidden    = "hidden"
+)
+
+// A hashtag or mention in the chirp body. Start and End are offsets in
+// Unicode code points and include the leading # or @.
+type Entity struct {
+	Type   entities.Kind `json:"type"`
+	Text   string        `json:"text"`
+	Start  int32         `json:"start"`
+	End    int32         `json:"end"`
+	UserID *uuid.UUID    `json:"user_id,omitempty"`
+}
+
+func chirpCreatedEvent(chirp database.Chirp) events.ChirpCreated {
+	created := events.ChirpCreated{
+		ID:        chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UserID:    chirp.UserID,
+		Body:      chirp.Body,
+		Hashtags:  chirpHashtags(chirp.Body),
+	}
+	if chirp.InReplyTo.Valid {
+		created.InReplyTo = &chirp.InReplyTo.UUID
+	}
+	return created
 }
 
 func (cfg *ApiConfig) addChirp(w http.ResponseWriter, r *http.Request) {
 	type createChirpRequest struct {
-		Body   string `json:"body"`
-		UserID string `json:"user_id"`
+		Body      string     `json:"body"`
+		UserID    string     `json:"user_id"`
+		InReplyTo *uuid.UUID `json:"in_reply_to"`
 	}
 	params, err := util.DecodeJSON[createChirpRequest](r)
 	if util.ErrorNotNil(err, w) {
 		return
 	}
 
-	// Check if user has a valid JWT
-	token, err := auth.GetBearerToken(r.Header)
-	if util.ErrorNotNil(err, w) {
+	// Check if user has a valid JWT or a token allowed to post
+	userID, err := cfg.authenticateScope(r, auth.ScopeChirpsWrite)
+	if err != nil {
+		respondAuthError(w, err)
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, cfg.JwtSecret)
-	if err != nil {
-		util.RespondWithError(w, 401, struct {
-			Error string `json:"error"`
-		}{Error: err.Error()})
+	user, ok := cfg.activeUser(w, r, userID)
+	if !ok {
+		return
+	}
+
+	if cfg.RequireVerifiedEmail && !user.EmailVerifiedAt.Valid {
+		util.RespondWithError(w, http.StatusForbidden, util.ResponseError{Error: "verify your email address before posting"})
 		return
 	}
 
@@ -61,88 +109,257 @@ func (cfg *ApiConfig) addChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	profaneList := []string{"kerfuffle", "sharbert", "fornax"}
-	cleanedBody := replaceProfane(params.Body, profaneList)
-	if util.ErrorNotNil(err, w) {
+	moderated := cfg.Moderator.Moderate(params.Body)
+	if moderated.Action == moderation.Reject {
+		util.RespondWithError(w, http.StatusUnprocessableEntity, struct {
+			Error   string   `json:"error"`
+			Reasons []string `json:"reasons"`
+		}{Error: "Chirp violates the content policy", Reasons: moderated.Reasons()})
+		return
+	}
+	// Replacements need not keep the length, so the limit holds for what is
+	// stored as well
+	if len(moderated.Body) > 140 {
+		util.RespondWithError(w, 400, util.ResponseError{
+			Error: "Chirp is too long",
+		})
 		return
 	}
 
 	createChirpParams := database.CreateChirpParams{
-		Body:   cleanedBody,
+		Body:   moderated.Body,
 		UserID: userID,
+		Status: chirpStatusPublished,
+	}
+	// Held chirps are stored but only shown to their author until reviewed
+	if moderated.Action == moderation.Review {
+		createChirpParams.Status = chirpStatusHeld
+	}
+
+	if params.InReplyTo != nil {
+		parent, err := cfg.DbQueries.GetChirpById(r.Context(), *params.InReplyTo)
+		if err == sql.ErrNoRows || (err == nil && (parent.DeletedAt.Valid || parent.Status != chirpStatusPublished)) {
+			util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Parent chirp not found"})
+			return
+		}
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+		// Replying to a rechirp continues the original conversation
+		if parent.RechirpOf.Valid {
+			parent.ID = parent.RechirpOf.UUID
+		}
+		createChirpParams.InReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
+
+	chirp, err := queries.CreateChirp(r.Context(), createChirpParams)
+	if util.ErrorNotNil(err, w) {
+		return
 	}
 
-	chirp, err := cfg.DbQueries.CreateChirp(r.Context(), createChirpParams)
+	err = saveEntities(r.Context(), queries, chirp)
 	if util.ErrorNotNil(err, w) {
 		return
 	}
 
-	chirpCreatedResponse := Chirp{
+	// Held chirps are not announced until they are published
+	if chirp.Status == chirpStatusPublished {
+		err = events.Publish(r.Context(), queries, chirpCreatedEvent(chirp))
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	responseChirps, err := cfg.chirpsResponse(r.Context(), []database.Chirp{chirp}, uuid.NullUUID{UUID: userID, Valid: true})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	if chirp.Status == chirpStatusHeld {
+		util.RespondWithJSON(w, http.StatusAccepted, responseChirps[0])
+		return
+	}
+	util.RespondWithJSON(w, 201, responseChirps[0])
+
+}
+
+type chirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+}
+
+func chirpResponse(chirp database.Chirp) Chirp {
+	response := Chirp{
 		ID:        chirp.ID,
 		CreatedAt: chirp.CreatedAt,
 		UpdatedAt: chirp.UpdatedAt,
 		Body:      chirp.Body,
 		UserID:    chirp.UserID,
+		Deleted:   chirp.DeletedAt.Valid,
+		Status:    chirp.Status,
 	}
-
-	util.RespondWithJSON(w, 201, chirpCreatedResponse)
-
+	if chirp.InReplyTo.Valid {
+		response.InReplyTo = &chirp.InReplyTo.UUID
+	}
+	return response
 }
 
-func (cfg *ApiConfig) getAllChirps(w http.ResponseWriter, r *http.Request) {
+// Builds the response for a batch of chirps, embedding the originals of
+// rechirps and loading engagement counts. The *_by_me flags are only set
+// when the request has an authenticated viewer.
+func (cfg *ApiConfig) chirpsResponse(ctx context.Context, chirps []database.Chirp, viewer uuid.NullUUID) ([]Chirp, error) {
+	ids := []uuid.UUID{}
+	originalIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+		if chirp.RechirpOf.Valid {
+			originalIDs = append(originalIDs, chirp.RechirpOf.UUID)
+		}
+	}
 
-	author := r.URL.Query().Get("author_id")
-	sort := r.URL.Query().Get("sort")
+	originals := map[uuid.UUID]database.Chirp{}
+	if len(originalIDs) > 0 {
+		rows, err := cfg.DbQueries.GetChirpsByIds(ctx, originalIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, original := range rows {
+			originals[original.ID] = original
+		}
+		ids = append(ids, originalIDs...)
+	}
+
+	rows, err := cfg.DbQueries.GetChirpEngagement(ctx, database.GetChirpEngagementParams{
+		ViewerID: viewer,
+		ChirpIds: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	engagement := map[uuid.UUID]database.GetChirpEngagementRow{}
+	for _, row := range rows {
+		engagement[row.ID] = row
+	}
 
-	var chirps []database.Chirp
-	var err error
+	chirpEntities, err := cfg.loadEntities(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
 
-	if author == "" {
-		if sort == "desc" {
-			chirps, err = cfg.DbQueries.GetAllChirpsDesc(r.Context())
-		} else {
-			chirps, err = cfg.DbQueries.GetAllChirpsAsc(r.Context())
+	build := func(chirp database.Chirp) Chirp {
+		response := chirpResponse(chirp)
+		stats := engagement[chirp.ID]
+		response.ReplyCount = stats.ReplyCount
+		response.LikeCount = stats.LikeCount
+		response.RechirpCount = stats.RechirpCount
+		response.Entities = chirpEntities[chirp.ID]
+		if response.Entities == nil {
+			response.Entities = []Entity{}
 		}
-		if err != nil {
-			util.RespondWithError(w, http.StatusInternalServerError, err.Error())
-			return
+		// Ancestors and rechirped originals can be held or hidden, keep
+		// their place in the response without leaking the body
+		if !chirp.DeletedAt.Valid && !chirpVisible(chirp, viewer) {
+			response.Body = ""
+			response.Entities = []Entity{}
 		}
-	} else {
-		author_uuid, err := uuid.Parse(author)
-		if err != nil {
-			util.RespondWithError(w, http.StatusUnauthorized, err.Error())
-			return
+		if viewer.Valid {
+			response.LikedByMe = &stats.LikedByViewer
+			response.RechirpedByMe = &stats.RechirpedByViewer
 		}
+		return response
+	}
 
-		if sort == "desc" {
-			chirps, err = cfg.DbQueries.GetChirpsByUserIdDesc(r.Context(), author_uuid)
-		} else {
-			chirps, err = cfg.DbQueries.GetChirpsByUserIdAsc(r.Context(), author_uuid)
+	responseChirps := []Chirp{}
+	for _, chirp := range chirps {
+		response := build(chirp)
+		if original, ok := originals[chirp.RechirpOf.UUID]; ok && chirp.RechirpOf.Valid {
+			embedded := build(original)
+			response.RechirpOf = &embedded
 		}
+		responseChirps = append(responseChirps, response)
+	}
+	return responseChirps, nil
+}
 
+func chirpCursor(chirp database.Chirp) cursor {
+	return cursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
+}
+
+func (cfg *ApiConfig) getAllChirps(w http.ResponseWriter, r *http.Request) {
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	author := uuid.NullUUID{}
+	if authorID := r.URL.Query().Get("author_id"); authorID != "" {
+		author.UUID, err = uuid.Parse(authorID)
 		if err != nil {
-			util.RespondWithError(w, http.StatusInternalServerError, err.Error())
+			util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid author_id"})
 			return
 		}
+		author.Valid = true
 	}
 
+	viewer := cfg.viewer(r)
+	after := func(c *cursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetChirpsAfterCursor(r.Context(), database.GetChirpsAfterCursorParams{
+			ViewerID:        viewer,
+			AuthorID:        author,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+	before := func(c *cursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetChirpsBeforeCursor(r.Context(), database.GetChirpsBeforeCursorParams{
+			ViewerID:        viewer,
+			AuthorID:        author,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+
+	cfg.respondWithChirpPage(w, r, page, after, before)
+}
+
+func (cfg *ApiConfig) respondWithChirpPage(w http.ResponseWriter, r *http.Request, page pageRequest, after, before keysetQuery[database.Chirp]) {
+	result, err := fetchPage(page, after, before, chirpCursor)
 	if err != nil {
-		util.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		util.RespondWithError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	responseChirps := []Chirp{}
-	for _, chirp := range chirps {
-		chirpResponse := Chirp{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
-		}
-		responseChirps = append(responseChirps, chirpResponse)
+	responseChirps, err := cfg.chirpsResponse(r.Context(), result.Items, cfg.viewer(r))
+	if err != nil {
+		util.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
-	util.RespondWithJSON(w, 200, responseChirps)
+
+	setLinkHeader(w, r, page, result)
+	util.RespondWithJSON(w, 200, chirpPage{
+		Chirps:     responseChirps,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
+	})
 }
 
 func (cfg *ApiConfig) getChirp(w http.ResponseWriter, r *http.Request) {
@@ -151,37 +368,33 @@ func (cfg *ApiConfig) getChirp(w http.ResponseWriter, r *http.Request) {
 	if util.ErrorNotNil(err, w) {
 		return
 	}
+	viewer := cfg.viewer(r)
 	chirp, err := cfg.DbQueries.GetChirpById(r.Context(), chirpUUID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			util.RespondWithError(w, http.StatusNotFound, struct {
-				Error string `json:"error"`
-			}{Error: "Chirp not found"})
-			return
-		}
+	if err == sql.ErrNoRows || (err == nil && !chirpVisible(chirp, viewer)) {
+		util.RespondWithError(w, http.StatusNotFound, struct {
+			Error string `json:"error"`
+		}{Error: "Chirp not found"})
+		return
 	}
-	responseChirp := Chirp{
-		ID:        chirp.ID,
-		CreatedAt: chirp.CreatedAt,
-		UpdatedAt: chirp.UpdatedAt,
-		Body:      chirp.Body,
-		UserID:    chirp.UserID,
+	if util.ErrorNotNil(err, w) {
+		return
 	}
-	util.RespondWithJSON(w, 200, responseChirp)
+
+	responseChirps, err := cfg.chirpsResponse(r.Context(), []database.Chirp{chirp}, viewer)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	util.RespondWithJSON(w, 200, responseChirps[0])
 
 }
 
-func replaceProfane(message string, profaneList []string) string {
-	words := strings.Split(message, " ")
-	cleanedWords := []string{}
-	for _, word := range words {
-		if util.SliceContains(profaneList, strings.ToLower(word)) {
-			cleanedWords = append(cleanedWords, "****")
-		} else {
-			cleanedWords = append(cleanedWords, word)
-		}
+// Deleted chirps are gone for everyone, chirps that are not published
+// can only be seen by their author
+func chirpVisible(chirp database.Chirp, viewer uuid.NullUUID) bool {
+	if chirp.DeletedAt.Valid {
+		return false
 	}
-	return strings.Join(cleanedWords, " ")
+	return chirp.Status == chirpStatusPublished || (viewer.Valid && viewer.UUID == chirp.UserID)
 }
 
 func (cfg *ApiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
@@ -193,6 +406,10 @@ func (cfg *ApiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 	}
 
 	chirp, err := cfg.DbQueries.GetChirpById(r.Context(), chirpUUID)
+	if err == sql.ErrNoRows || (err == nil && chirp.DeletedAt.Valid) {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Chirp not found"})
+		return
+	}
 	if util.ErrorNotNil(err, w) {
 		return
 	}
@@ -209,9 +426,9 @@ func (cfg *ApiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := auth.ValidateJWT(accessToken, cfg.JwtSecret)
+	userID, err := cfg.authenticateScope(r, auth.ScopeChirpsWrite)
 	if err != nil {
-		util.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		respondAuthError(w, err)
 		return
 	}
 
@@ -222,12 +439,50 @@ func (cfg *ApiConfig) deleteChirp(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = cfg.DbQueries.DeleteChirpById(r.Context(), chirpUUID)
+	// Replies keep pointing at a tombstone so the thread stays intact
+	hasReplies, err := cfg.DbQueries.ChirpHasReplies(r.Context(), chirpUUID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
+
+	if hasReplies {
+		err = queries.TombstoneChirp(r.Context(), chirpUUID)
+		if err == nil {
+			err = queries.DeleteRechirpsOf(r.Context(), chirpUUID)
+		}
+	} else {
+		err = queries.DeleteChirpById(r.Context(), chirpUUID)
+	}
 	if err != nil {
 		util.RespondWithError(w, http.StatusNotFound, err.Error())
 		return
 	}
 
+	// Only chirps that were announced as created are announced as deleted,
+	// held or hidden chirps and tombstones were never or are no longer out
+	if chirp.Status == chirpStatusPublished && !chirp.DeletedAt.Valid {
+		err = events.Publish(r.Context(), queries, events.ChirpDeleted{
+			ID:       chirp.ID,
+			UserID:   chirp.UserID,
+			Hashtags: chirpHashtags(chirp.Body),
+		})
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
 	util.RespondWithJSON(w, http.StatusNoContent, struct {
 		Error string `json:"error"`
 	}{Error: "delete successful"})
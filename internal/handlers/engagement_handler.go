@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+	"chirpy/util"
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func EngagementRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("POST /api/chirps/{chirpID}/like", http.HandlerFunc(apiConfig.likeChirp))
+	s.Handle("DELETE /api/chirps/{chirpID}/like", http.HandlerFunc(apiConfig.unlikeChirp))
+	s.Handle("POST /api/chirps/{chirpID}/rechirp", http.HandlerFunc(apiConfig.rechirp))
+	s.Handle("DELETE /api/chirps/{chirpID}/rechirp", http.HandlerFunc(apiConfig.undoRechirp))
+}
+
+// Looks up the chirp named in the path. Engaging with a rechirp engages
+// with the chirp it points at, so the original is returned in that case.
+// Only published chirps can be engaged with, but likes and rechirps of any
+// chirp can be taken back (undoing is true), even once it was held, hidden
+// or deleted.
+func (cfg *ApiConfig) engagementTarget(w http.ResponseWriter, r *http.Request, undoing bool) (database.Chirp, bool) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid chirp id"})
+		return database.Chirp{}, false
+	}
+
+	chirp, err := cfg.DbQueries.GetChirpById(r.Context(), chirpID)
+	if err == nil && chirp.RechirpOf.Valid {
+		chirp, err = cfg.DbQueries.GetChirpById(r.Context(), chirp.RechirpOf.UUID)
+	}
+	visible := chirp.Status == chirpStatusPublished && !chirp.DeletedAt.Valid
+	if err == sql.ErrNoRows || (err == nil && !visible && !undoing) {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Chirp not found"})
+		return database.Chirp{}, false
+	}
+	if util.ErrorNotNil(err, w) {
+		return database.Chirp{}, false
+	}
+
+	return chirp, true
+}
+
+func (cfg *ApiConfig) likeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	chirp, ok := cfg.engagementTarget(w, r, false)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
+
+	liked, err := queries.LikeChirp(r.Context(), database.LikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	// Liking again changes nothing and is not announced
+	if liked == 1 {
+		err = events.Publish(r.Context(), queries, events.ChirpLiked{
+			ChirpID:  chirp.ID,
+			AuthorID: chirp.UserID,
+			UserID:   userID,
+		})
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) unlikeChirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	chirp, ok := cfg.engagementTarget(w, r, true)
+	if !ok {
+		return
+	}
+
+	err = cfg.DbQueries.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+		UserID:  userID,
+		ChirpID: chirp.ID,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) rechirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	original, ok := cfg.engagementTarget(w, r, false)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
+
+	status := http.StatusCreated
+	rechirp, err := queries.CreateRechirp(r.Context(), database.CreateRechirpParams{
+		UserID:    userID,
+		RechirpOf: original.ID,
+	})
+	if err == nil {
+		err = events.Publish(r.Context(), queries, events.ChirpRechirped{
+			ID:       rechirp.ID,
+			ChirpID:  original.ID,
+			AuthorID: original.UserID,
+			UserID:   userID,
+		})
+	} else if err == sql.ErrNoRows {
+		// Already rechirped, hand back the existing one
+		status = http.StatusOK
+		rechirp, err = queries.GetRechirp(r.Context(), database.GetRechirpParams{
+			UserID:    userID,
+			RechirpOf: original.ID,
+		})
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = tx.Commit()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	responseChirps, err := cfg.chirpsResponse(r.Context(), []database.Chirp{rechirp}, uuid.NullUUID{UUID: userID, Valid: true})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	util.RespondWithJSON(w, status, responseChirps[0])
+}
+
+func (cfg *ApiConfig) undoRechirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	original, ok := cfg.engagementTarget(w, r, true)
+	if !ok {
+		return
+	}
+
+	err = cfg.DbQueries.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+		UserID:    userID,
+		RechirpOf: original.ID,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
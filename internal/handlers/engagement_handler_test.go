@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestEngagement(t *testing.T) {
+	user := database.User{ID: uuid.New(), Email: "user@example.com", Role: "user"}
+	author := uuid.New()
+
+	published := testChirp(author, 0)
+	held := testChirp(author, 1)
+	held.Status = chirpStatusHeld
+	hidden := testChirp(author, 2)
+	hidden.Status = chirpStatusHidden
+	deleted := testChirp(author, 3)
+	deleted.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	rechirp := testChirp(uuid.New(), 4)
+	rechirp.RechirpOf = uuid.NullUUID{UUID: published.ID, Valid: true}
+
+	tests := []struct {
+		name     string
+		method   string
+		action   string
+		chirp    string
+		signedIn bool
+		wantCode int
+		// The query changing the engagement, when one should be made
+		wantQuery string
+		// The chirp engaged with
+		wantChirp uuid.UUID
+	}{
+		{"like", "POST", "like", published.ID.String(), true, http.StatusNoContent, "LikeChirp", published.ID},
+		{"like a rechirp", "POST", "like", rechirp.ID.String(), true, http.StatusNoContent, "LikeChirp", published.ID},
+		{"like signed out", "POST", "like", published.ID.String(), false, http.StatusUnauthorized, "", uuid.Nil},
+		{"like held", "POST", "like", held.ID.String(), true, http.StatusNotFound, "", uuid.Nil},
+		{"like hidden", "POST", "like", hidden.ID.String(), true, http.StatusNotFound, "", uuid.Nil},
+		{"like deleted", "POST", "like", deleted.ID.String(), true, http.StatusNotFound, "", uuid.Nil},
+		{"like unknown", "POST", "like", uuid.NewString(), true, http.StatusNotFound, "", uuid.Nil},
+		{"like invalid id", "POST", "like", "chirp", true, http.StatusBadRequest, "", uuid.Nil},
+		{"unlike", "DELETE", "like", published.ID.String(), true, http.StatusNoContent, "UnlikeChirp", published.ID},
+		{"unlike hidden", "DELETE", "like", hidden.ID.String(), true, http.StatusNoContent, "UnlikeChirp", hidden.ID},
+		{"unlike deleted", "DELETE", "like", deleted.ID.String(), true, http.StatusNoContent, "UnlikeChirp", deleted.ID},
+		{"unlike unknown", "DELETE", "like", uuid.NewString(), true, http.StatusNotFound, "", uuid.Nil},
+		{"rechirp", "POST", "rechirp", published.ID.String(), true, http.StatusCreated, "CreateRechirp", published.ID},
+		{"rechirp a rechirp", "POST", "rechirp", rechirp.ID.String(), true, http.StatusCreated, "CreateRechirp", published.ID},
+		{"rechirp held", "POST", "rechirp", held.ID.String(), true, http.StatusNotFound, "", uuid.Nil},
+		{"undo rechirp", "DELETE", "rechirp", published.ID.String(), true, http.StatusNoContent, "DeleteRechirp", published.ID},
+		{"undo rechirp of hidden", "DELETE", "rechirp", hidden.ID.String(), true, http.StatusNoContent, "DeleteRechirp", hidden.ID},
+		{"undo rechirp signed out", "DELETE", "rechirp", published.ID.String(), false, http.StatusUnauthorized, "", uuid.Nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t)
+			cfg, server := newTestServer(t, db, EngagementRoutes)
+			token := signIn(t, cfg, db, user)[0]
+			if !tt.signedIn {
+				token = ""
+			}
+
+			chirpsByID(db, published, held, hidden, deleted, rechirp)
+			noChirpDetails(db)
+			db.on("LikeChirp", returns(affected(1)))
+			db.on("UnlikeChirp", returns(affected(1)))
+			db.on("CreateRechirp", func(args []any) fakeResult {
+				created := testChirp(args[0].(uuid.UUID), 5)
+				created.RechirpOf = uuid.NullUUID{UUID: args[1].(uuid.UUID), Valid: true}
+				return rows(created)
+			})
+			db.on("DeleteRechirp", returns(affected(1)))
+			db.on("InsertOutboxEvent", returns(affected(1)))
+
+			w := serve(server, tt.method, "/api/chirps/"+tt.chirp+"/"+tt.action, token, nil)
+			if w.Code != tt.wantCode {
+				t.Fatalf("%s %s = %d %s, want %d", tt.method, tt.action, w.Code, w.Body, tt.wantCode)
+			}
+
+			for _, query := range []string{"LikeChirp", "UnlikeChirp", "CreateRechirp", "DeleteRechirp"} {
+				calls := db.called(query)
+				if query != tt.wantQuery {
+					if len(calls) != 0 {
+						t.Errorf("%s was called", query)
+					}
+					continue
+				}
+				if len(calls) != 1 || !calls[0].Committed {
+					t.Fatalf("%s calls = %+v, want one, committed", query, calls)
+				}
+				if calls[0].Args[0] != user.ID || calls[0].Args[1] != tt.wantChirp {
+					t.Errorf("%s args = %v, want %v engaging with %v", query, calls[0].Args, user.ID, tt.wantChirp)
+				}
+			}
+		})
+	}
+}
+
+func TestEngagementEvents(t *testing.T) {
+	user := database.User{ID: uuid.New(), Email: "user@example.com", Role: "user"}
+	chirp := testChirp(uuid.New(), 0)
+	existing := testChirp(user.ID, 1)
+	existing.RechirpOf = uuid.NullUUID{UUID: chirp.ID, Valid: true}
+
+	tests := []struct {
+		name   string
+		action string
+		// Whether the user engaged with the chirp before
+		again     bool
+		wantCode  int
+		wantEvent string
+	}{
+		{"like", "like", false, http.StatusNoContent, events.TypeChirpLiked},
+		{"like again", "like", true, http.StatusNoContent, ""},
+		{"rechirp", "rechirp", false, http.StatusCreated, events.TypeChirpRechirped},
+		{"rechirp again", "rechirp", true, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t)
+			cfg, server := newTestServer(t, db, EngagementRoutes)
+			token := signIn(t, cfg, db, user)[0]
+
+			chirpsByID(db, chirp)
+			noChirpDetails(db)
+			if tt.again {
+				db.on("LikeChirp", returns(affected(0)))
+				db.on("CreateRechirp", returns(rows()))
+			} else {
+				db.on("LikeChirp", returns(affected(1)))
+				db.on("CreateRechirp", returns(rows(existing)))
+			}
+			db.on("GetRechirp", returns(rows(existing)))
+			db.on("InsertOutboxEvent", returns(affected(1)))
+
+			w := serve(server, "POST", "/api/chirps/"+chirp.ID.String()+"/"+tt.action, token, nil)
+			if w.Code != tt.wantCode {
+				t.Fatalf("POST %s = %d %s, want %d", tt.action, w.Code, w.Body, tt.wantCode)
+			}
+			if tt.action == "rechirp" {
+				var response Chirp
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatal(err)
+				}
+				if response.ID != existing.ID {
+					t.Errorf("rechirp = %v, want %v", response.ID, existing.ID)
+				}
+			}
+
+			published := db.called("InsertOutboxEvent")
+			if tt.wantEvent == "" {
+				if len(published) != 0 {
+					t.Errorf("published %v", published[0].Args[1])
+				}
+				return
+			}
+			if len(published) != 1 || published[0].Args[1] != tt.wantEvent || !published[0].Committed {
+				t.Fatalf("published %+v, want one committed %s", published, tt.wantEvent)
+			}
+			var event struct {
+				ChirpID  uuid.UUID `json:"chirp_id"`
+				AuthorID uuid.UUID `json:"author_id"`
+				UserID   uuid.UUID `json:"user_id"`
+			}
+			if err := json.Unmarshal(published[0].Args[2].(json.RawMessage), &event); err != nil {
+				t.Fatal(err)
+			}
+			if event.ChirpID != chirp.ID || event.AuthorID != chirp.UserID || event.UserID != user.ID {
+				t.Errorf("event = %+v, want %v engaged with by %v", event, chirp.ID, user.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"chirpy/util"
+	"context"
+	"net/http"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+func EntityRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("GET /api/tags/{tag}/chirps", http.HandlerFunc(apiConfig.getTagChirps))
+	s.Handle("GET /api/users/{userID}/mentions", http.HandlerFunc(apiConfig.getMentions))
+}
+
+// The distinct hashtags in a chirp body, folded
+func chirpHashtags(body string) []string {
+	tags := []string{}
+	for _, entity := range entities.Parse(body) {
+		if entity.Kind == entities.Hashtag && !util.SliceContains(tags, entity.Text) {
+			tags = append(tags, entity.Text)
+		}
+	}
+	return tags
+}
+
+// Parses the chirp body for hashtags and mentions and stores them,
+// resolving mentioned handles to users where they exist
+func saveEntities(ctx context.Context, queries *database.Queries, chirp database.Chirp) error {
+	found := entities.Parse(chirp.Body)
+
+	handles := []string{}
+	for _, entity := range found {
+		if entity.Kind == entities.Mention {
+			handles = append(handles, entity.Text)
+		}
+	}
+
+	users := map[string]uuid.UUID{}
+	if len(handles) > 0 {
+		rows, err := queries.GetUsersByHandles(ctx, handles)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			users[row.Handle.String] = row.ID
+		}
+	}
+
+	for _, entity := range found {
+		var err error
+		switch entity.Kind {
+		case entities.Hashtag:
+			err = queries.CreateChirpHashtag(ctx, database.CreateChirpHashtagParams{
+				ChirpID:     chirp.ID,
+				Tag:         entity.Text,
+				StartOffset: int32(entity.Start),
+				EndOffset:   int32(entity.End),
+			})
+		case entities.Mention:
+			userID, ok := users[entity.Text]
+			err = queries.CreateChirpMention(ctx, database.CreateChirpMentionParams{
+				ChirpID:     chirp.ID,
+				UserID:      uuid.NullUUID{UUID: userID, Valid: ok},
+				Handle:      entity.Text,
+				StartOffset: int32(entity.Start),
+				EndOffset:   int32(entity.End),
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Loads the stored entities of a batch of chirps, ordered by offset
+func (cfg *ApiConfig) loadEntities(ctx context.Context, chirpIDs []uuid.UUID) (map[uuid.UUID][]Entity, error) {
+	hashtags, err := cfg.DbQueries.GetChirpHashtags(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	mentions, err := cfg.DbQueries.GetChirpMentions(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	chirpEntities := map[uuid.UUID][]Entity{}
+	for _, hashtag := range hashtags {
+		chirpEntities[hashtag.ChirpID] = append(chirpEntities[hashtag.ChirpID], Entity{
+			Type:  entities.Hashtag,
+			Text:  hashtag.Tag,
+			Start: hashtag.StartOffset,
+			End:   hashtag.EndOffset,
+		})
+	}
+	for _, mention := range mentions {
+		entity := Entity{
+			Type:  entities.Mention,
+			Text:  mention.Handle,
+			Start: mention.StartOffset,
+			End:   mention.EndOffset,
+		}
+		if mention.UserID.Valid {
+			entity.UserID = &mention.UserID.UUID
+		}
+		chirpEntities[mention.ChirpID] = append(chirpEntities[mention.ChirpID], entity)
+	}
+
+	for _, list := range chirpEntities {
+		sort.Slice(list, func(i, j int) bool { return list[i].Start < list[j].Start })
+	}
+	return chirpEntities, nil
+}
+
+func (cfg *ApiConfig) getTagChirps(w http.ResponseWriter, r *http.Request) {
+	tag := entities.Fold(r.PathValue("tag"))
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	viewer := cfg.viewer(r)
+	after := func(c *cursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetTagChirpsAfterCursor(r.Context(), database.GetTagChirpsAfterCursorParams{
+			ViewerID:        viewer,
+			Tag:             tag,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+	before := func(c *cursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetTagChirpsBeforeCursor(r.Context(), database.GetTagChirpsBeforeCursorParams{
+			ViewerID:        viewer,
+			Tag:             tag,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+
+	cfg.respondWithChirpPage(w, r, page, after, before)
+}
+
+func (cfg *ApiConfig) getMentions(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid user id"})
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	viewer := cfg.viewer(r)
+	after := func(c *cursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetMentionChirpsAfterCursor(r.Context(), database.GetMentionChirpsAfterCursorParams{
+			ViewerID:        viewer,
+			UserID:          userID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+	before := func(c *cursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetMentionChirpsBeforeCursor(r.Context(), database.GetMentionChirpsBeforeCursorParams{
+			ViewerID:        viewer,
+			UserID:          userID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+
+	cfg.respondWithChirpPage(w, r, page, after, before)
+}
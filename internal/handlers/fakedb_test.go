@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// A database/sql driver answering the queries of one test. Queries are
+// told apart by the "-- name: X" line sqlc starts each of them with, and
+// every query the test did not expect fails it.
+type fakeDB struct {
+	t       *testing.T
+	mu      sync.Mutex
+	queries map[string]fakeQuery
+	calls   []*fakeCall
+}
+
+// Answers a query given the arguments it was run with. Arguments are the
+// values handed to the sqlc methods, uuid.NullUUID or pq.Array and all.
+type fakeQuery func(args []any) fakeResult
+
+type fakeResult struct {
+	rows     [][]driver.Value
+	affected int64
+	err      error
+}
+
+type fakeCall struct {
+	Name string
+	Args []any
+	// Whether the query's effects were kept: at once outside a
+	// transaction, on commit inside one
+	Committed bool
+}
+
+func newFakeDB(t *testing.T) *fakeDB {
+	return &fakeDB{t: t, queries: map[string]fakeQuery{}}
+}
+
+func (db *fakeDB) on(name string, query fakeQuery) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.queries[name] = query
+}
+
+// The calls made to the named query, in order
+func (db *fakeDB) called(name string) []fakeCall {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	calls := []fakeCall{}
+	for _, call := range db.calls {
+		if call.Name == name {
+			calls = append(calls, *call)
+		}
+	}
+	return calls
+}
+
+func (db *fakeDB) run(query string, args []driver.NamedValue, tx *fakeTx) fakeResult {
+	name, _, _ := strings.Cut(strings.TrimPrefix(query, "-- name: "), " ")
+
+	call := &fakeCall{Name: name, Committed: tx == nil}
+	for _, arg := range args {
+		call.Args = append(call.Args, arg.Value)
+	}
+
+	db.mu.Lock()
+	db.calls = append(db.calls, call)
+	answer, ok := db.queries[name]
+	db.mu.Unlock()
+	if tx != nil {
+		tx.calls = append(tx.calls, call)
+	}
+
+	if !ok {
+		db.t.Errorf("unexpected query %s", name)
+		return fakeResult{err: errors.New("unexpected query " + name)}
+	}
+	return answer(call.Args)
+}
+
+func (db *fakeDB) Connect(ctx context.Context) (driver.Conn, error) {
+	return &fakeConn{db: db}, nil
+}
+
+func (db *fakeDB) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("open fake databases with sql.OpenDB")
+}
+
+type fakeConn struct {
+	db *fakeDB
+	tx *fakeTx
+}
+
+type fakeTx struct {
+	conn  *fakeConn
+	calls []*fakeCall
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.conn.db.mu.Lock()
+	defer tx.conn.db.mu.Unlock()
+	for _, call := range tx.calls {
+		call.Committed = true
+	}
+	tx.conn.tx = nil
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.conn.tx = nil
+	return nil
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("the fake database does not prepare statements")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.tx = &fakeTx{conn: c}
+	return c.tx, nil
+}
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.Begin()
+}
+
+// Hands the arguments to the queries as they are
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result := c.db.run(query, args, c.tx)
+	if result.err != nil {
+		return nil, result.err
+	}
+	return &fakeRows{rows: result.rows}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result := c.db.run(query, args, c.tx)
+	if result.err != nil {
+		return nil, result.err
+	}
+	return driver.RowsAffected(result.affected), nil
+}
+
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+	columns := []string{}
+	for i := range r.rows[0] {
+		columns = append(columns, "column"+strconv.Itoa(i))
+	}
+	return columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// Rows holding items. Structs become a column per field, in the order
+// sqlc scans them; anything else is a row of one column.
+func rows(items ...any) fakeResult {
+	result := fakeResult{rows: [][]driver.Value{}}
+	for _, item := range items {
+		v := reflect.ValueOf(item)
+		if v.Kind() != reflect.Struct || v.Type() == reflect.TypeOf(time.Time{}) || isValuer(item) {
+			result.rows = append(result.rows, []driver.Value{column(item)})
+			continue
+		}
+		row := []driver.Value{}
+		for i := range v.NumField() {
+			row = append(row, column(v.Field(i).Interface()))
+		}
+		result.rows = append(result.rows, row)
+	}
+	return result
+}
+
+func isValuer(v any) bool {
+	_, ok := v.(driver.Valuer)
+	return ok
+}
+
+func column(v any) driver.Value {
+	if s, ok := v.([]string); ok {
+		v = pq.Array(s)
+	}
+	value, err := driver.DefaultParameterConverter.ConvertValue(v)
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func affected(n int64) fakeResult {
+	return fakeResult{affected: n}
+}
+
+func failed(err error) fakeResult {
+	return fakeResult{err: err}
+}
+
+// Answers every call with the same result
+func returns(result fakeResult) fakeQuery {
+	return func([]any) fakeResult { return result }
+}
+
+// An ApiConfig on the fake database, with routes registered by each of
+// routes
+func newTestServer(t *testing.T, db *fakeDB, routes ...func(*http.ServeMux, *ApiConfig)) (*ApiConfig, http.Handler) {
+	keys := auth.NewKeySet("chirpy", "chirpy")
+	if err := keys.Add(auth.NewHMACKey("test", []byte("secret")), true); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := sql.OpenDB(db)
+	t.Cleanup(func() { conn.Close() })
+	cfg := &ApiConfig{
+		DB:        conn,
+		DbQueries: database.New(conn),
+		Keys:      keys,
+	}
+
+	mux := http.NewServeMux()
+	for _, route := range routes {
+		route(mux, cfg)
+	}
+	return cfg, mux
+}
+
+// Makes the database know the users and returns a bearer token for each
+func signIn(t *testing.T, cfg *ApiConfig, db *fakeDB, users ...database.User) []string {
+	byID := map[uuid.UUID]database.User{}
+	tokens := []string{}
+	for _, user := range users {
+		byID[user.ID] = user
+		token, err := auth.MakeJWT(user.ID, auth.RoleUser, uuid.New(), cfg.Keys, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	db.on("SessionActive", returns(rows(true)))
+	db.on("GetUserById", func(args []any) fakeResult {
+		user, ok := byID[args[0].(uuid.UUID)]
+		if !ok {
+			return rows()
+		}
+		return rows(user)
+	})
+	return tokens
+}
+
+func serve(handler http.Handler, method, target, token string, body io.Reader) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, body)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+// Answers the queries chirpsResponse makes with no engagement and no
+// entities
+func noChirpDetails(db *fakeDB) {
+	db.on("GetChirpEngagement", returns(rows()))
+	db.on("GetChirpHashtags", returns(rows()))
+	db.on("GetChirpMentions", returns(rows()))
+}
+
+// Answers GetChirpById and GetChirpsByIds with the chirps
+func chirpsByID(db *fakeDB, chirps ...database.Chirp) {
+	db.on("GetChirpById", func(args []any) fakeResult {
+		for _, chirp := range chirps {
+			if chirp.ID == args[0].(uuid.UUID) {
+				return rows(chirp)
+			}
+		}
+		return rows()
+	})
+	db.on("GetChirpsByIds", func(args []any) fakeResult {
+		ids := args[0].(pq.GenericArray).A.([]uuid.UUID)
+		found := []any{}
+		for _, chirp := range chirps {
+			if slices.Contains(ids, chirp.ID) {
+				found = append(found, chirp)
+			}
+		}
+		return rows(found...)
+	})
+}
+
+// A published chirp posted minutes after a fixed time
+func testChirp(userID uuid.UUID, minutes int) database.Chirp {
+	createdAt := time.Date(2024, 1, 1, 12, minutes, 0, 0, time.UTC)
+	return database.Chirp{
+		ID:          uuid.New(),
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+		Body:        "chirp " + strconv.Itoa(minutes),
+		UserID:      userID,
+		Status:      chirpStatusPublished,
+		PublishedAt: sql.NullTime{Time: createdAt, Valid: true},
+	}
+}
+
+// Answers a keyset query over chirps sorted by (created_at, id) the way
+// the *AfterCursor queries do, or the *BeforeCursor ones when before is set
+func keysetChirps(chirps []database.Chirp, before bool, cursorCreatedAt, cursorID, limit any) fakeResult {
+	createdAt, id := cursorCreatedAt.(sql.NullTime), cursorID.(uuid.NullUUID)
+	sorted := slices.Clone(chirps)
+	slices.SortFunc(sorted, func(a, b database.Chirp) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID.String(), b.ID.String())
+	})
+	if before {
+		slices.Reverse(sorted)
+	}
+
+	page := []any{}
+	for _, chirp := range sorted {
+		if createdAt.Valid {
+			c := chirp.CreatedAt.Compare(createdAt.Time)
+			if c == 0 {
+				c = strings.Compare(chirp.ID.String(), id.UUID.String())
+			}
+			if (before && c >= 0) || (!before && c <= 0) {
+				continue
+			}
+		}
+		if len(page) == int(limit.(int32)) {
+			break
+		}
+		page = append(page, chirp)
+	}
+	return rows(page...)
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"chirpy/internal/database"
+	"chirpy/util"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func FollowRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("POST /api/users/{userID}/follow", http.HandlerFunc(apiConfig.followUser))
+	s.Handle("DELETE /api/users/{userID}/follow", http.HandlerFunc(apiConfig.unfollowUser))
+	s.Handle("GET /api/users/{userID}/followers", http.HandlerFunc(apiConfig.getFollowers))
+	s.Handle("GET /api/users/{userID}/following", http.HandlerFunc(apiConfig.getFollowing))
+	s.Handle("GET /api/timeline", http.HandlerFunc(apiConfig.getTimeline))
+}
+
+type FollowedUser struct {
+	ID         uuid.UUID `json:"id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type followPage struct {
+	Users      []FollowedUser `json:"users"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	PrevCursor string         `json:"prev_cursor,omitempty"`
+}
+
+func (cfg *ApiConfig) followUser(w http.ResponseWriter, r *http.Request) {
+	followerID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid user id"})
+		return
+	}
+
+	if followerID == followeeID {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "users cannot follow themselves"})
+		return
+	}
+
+	_, err = cfg.DbQueries.GetUserById(r.Context(), followeeID)
+	if err == sql.ErrNoRows {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "User not found"})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = cfg.DbQueries.FollowUser(r.Context(), database.FollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	followerID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid user id"})
+		return
+	}
+
+	err = cfg.DbQueries.UnfollowUser(r.Context(), database.UnfollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) getFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid user id"})
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	after := func(c *cursor, limit int32) ([]FollowedUser, error) {
+		createdAt, id := c.params()
+		rows, err := cfg.DbQueries.GetFollowersAfterCursor(r.Context(), database.GetFollowersAfterCursorParams{
+			UserID:          userID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+		users := []FollowedUser{}
+		for _, row := range rows {
+			users = append(users, FollowedUser{ID: row.FollowerID, FollowedAt: row.CreatedAt})
+		}
+		return users, err
+	}
+	before := func(c *cursor, limit int32) ([]FollowedUser, error) {
+		createdAt, id := c.params()
+		rows, err := cfg.DbQueries.GetFollowersBeforeCursor(r.Context(), database.GetFollowersBeforeCursorParams{
+			UserID:          userID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+		users := []FollowedUser{}
+		for _, row := range rows {
+			users = append(users, FollowedUser{ID: row.FollowerID, FollowedAt: row.CreatedAt})
+		}
+		return users, err
+	}
+
+	cfg.respondWithFollowPage(w, r, page, after, before)
+}
+
+func (cfg *ApiConfig) getFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid user id"})
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	after := func(c *cursor, limit int32) ([]FollowedUser, error) {
+		createdAt, id := c.params()
+		rows, err := cfg.DbQueries.GetFollowingAfterCursor(r.Context(), database.GetFollowingAfterCursorParams{
+			UserID:          userID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+		users := []FollowedUser{}
+		for _, row := range rows {
+			users = append(users, FollowedUser{ID: row.FolloweeID, FollowedAt: row.CreatedAt})
+		}
+		return users, err
+	}
+	before := func(c *cursor, limit int32) ([]FollowedUser, error) {
+		createdAt, id := c.params()
+		rows, err := cfg.DbQueries.GetFollowingBeforeCursor(r.Context(), database.GetFollowingBeforeCursorParams{
+			UserID:          userID,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+		users := []FollowedUser{}
+		for _, row := range rows {
+			users = append(users, FollowedUser{ID: row.FolloweeID, FollowedAt: row.CreatedAt})
+		}
+		return users, err
+	}
+
+	cfg.respondWithFollowPage(w, r, page, after, before)
+}
+
+func (cfg *ApiConfig) respondWithFollowPage(w http.ResponseWriter, r *http.Request, page pageRequest, after, before keysetQuery[FollowedUser]) {
+	result, err := fetchPage(page, after, before, func(u FollowedUser) cursor {
+		return cursor{CreatedAt: u.FollowedAt, ID: u.ID}
+	})
+	if err != nil {
+		util.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	setLinkHeader(w, r, page, result)
+	util.RespondWithJSON(w, 200, followPage{
+		Users:      result.Items,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
+	})
+}
+
+// Chirps from the users the caller follows, with the same sort and
+// pagination semantics as GET /api/chirps
+func (cfg *ApiConfig) getTimeline(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	after := func(c *cursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetTimelineAfterCursor(r.Context(), database.GetTimelineAfterCursorParams{
+			FollowerID:      userID,
+			ViewerID:        uuid.NullUUID{UUID: userID, Valid: true},
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+	before := func(c *cursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetTimelineBeforeCursor(r.Context(), database.GetTimelineBeforeCursorParams{
+			FollowerID:      userID,
+			ViewerID:        uuid.NullUUID{UUID: userID, Valid: true},
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+
+	cfg.respondWithChirpPage(w, r, page, after, before)
+}
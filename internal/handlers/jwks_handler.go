@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"chirpy/util"
+	"net/http"
+)
+
+func JWKSRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("GET /.well-known/jwks.json", http.HandlerFunc(apiConfig.getJWKS))
+}
+
+// Publishes the public keys access tokens can be verified with, so other
+// services can check them without sharing a secret
+func (cfg *ApiConfig) getJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	util.RespondWithJSON(w, 200, cfg.Keys.JWKS())
+}
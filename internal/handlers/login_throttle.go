@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"chirpy/internal/throttle"
+	"chirpy/util"
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policies for LoginThrottle. Many users can share an IP behind a NAT, so
+// IPs get far more attempts than a single account.
+var (
+	AccountThrottlePolicy = throttle.Policy{
+		FreeAttempts:     3,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Minute,
+		LockoutThreshold: 10,
+		LockoutDuration:  15 * time.Minute,
+		Window:           24 * time.Hour,
+	}
+	IPThrottlePolicy = throttle.Policy{
+		FreeAttempts:     20,
+		BaseDelay:        time.Second,
+		MaxDelay:         5 * time.Minute,
+		LockoutThreshold: 100,
+		LockoutDuration:  time.Hour,
+		Window:           24 * time.Hour,
+	}
+)
+
+// Counts failed logins per account and per client IP. Accounts are keyed
+// by email so guesses against addresses without an account are slowed
+// down just the same.
+type LoginThrottle struct {
+	Accounts *throttle.Throttler
+	IPs      *throttle.Throttler
+}
+
+func NewLoginThrottle(store throttle.Store) *LoginThrottle {
+	return &LoginThrottle{
+		Accounts: throttle.New(store, AccountThrottlePolicy),
+		IPs:      throttle.New(store, IPThrottlePolicy),
+	}
+}
+
+func accountThrottleKey(email string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func ipThrottleKey(r *http.Request) string {
+	return "ip:" + requestDevice(r, "").IP
+}
+
+// Tells whether a login for email may be attempted from the request's IP
+// and, when it may, counts it as failed for both until Release. When both
+// are throttled the longer wait is picked.
+func (t *LoginThrottle) Attempt(ctx context.Context, r *http.Request, email string) (throttle.Decision, error) {
+	account, err := t.Accounts.Attempt(ctx, accountThrottleKey(email))
+	if err != nil || !account.Allowed() {
+		return account, err
+	}
+	ip, err := t.IPs.Attempt(ctx, ipThrottleKey(r))
+	if err != nil {
+		return ip, err
+	}
+	if !ip.Allowed() {
+		return ip, t.Accounts.Release(ctx, accountThrottleKey(email))
+	}
+	return throttle.Decision{}, nil
+}
+
+// Takes back an attempt once the credentials turned out to be right
+func (t *LoginThrottle) Release(ctx context.Context, r *http.Request, email string) error {
+	err := t.Accounts.Release(ctx, accountThrottleKey(email))
+	if err != nil {
+		return err
+	}
+	return t.IPs.Release(ctx, ipThrottleKey(r))
+}
+
+// Clears the account's failures. The IP's are kept, or an attacker could
+// clear them by signing in to an account of their own.
+func (t *LoginThrottle) Succeed(ctx context.Context, email string) error {
+	return t.Accounts.Reset(ctx, accountThrottleKey(email))
+}
+
+func retryAfterSeconds(decision throttle.Decision) int {
+	return int(math.Ceil(decision.RetryAfter.Seconds()))
+}
+
+func throttledMessage(decision throttle.Decision) string {
+	if decision.Locked {
+		return "too many failed login attempts, the account is temporarily locked"
+	}
+	return "too many failed login attempts, try again later"
+}
+
+// Responds 429 with a Retry-After header unless the login may go ahead,
+// reporting whether it may. An attempt that goes ahead counts as failed
+// until the handler calls LoginThrottle.Release.
+func (cfg *ApiConfig) loginAllowed(w http.ResponseWriter, r *http.Request, email string) bool {
+	decision, err := cfg.LoginThrottle.Attempt(r.Context(), r, email)
+	if util.ErrorNotNil(err, w) {
+		return false
+	}
+	if decision.Allowed() {
+		return true
+	}
+
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(decision)))
+	util.RespondWithError(w, http.StatusTooManyRequests, struct {
+		Error      string `json:"error"`
+		RetryAfter int    `json:"retry_after"`
+	}{Error: throttledMessage(decision), RetryAfter: retryAfterSeconds(decision)})
+	return false
+}
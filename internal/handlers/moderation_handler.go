@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+	"chirpy/util"
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func ModerationRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("POST /api/chirps/{chirpID}/report", http.HandlerFunc(apiConfig.reportChirp))
+
+	s.Handle("GET /admin/moderation/reports", apiConfig.requireRole(auth.RoleModerator, apiConfig.getReports))
+	s.Handle("POST /admin/moderation/reports/{reportID}", apiConfig.requireRole(auth.RoleModerator, apiConfig.resolveReport))
+	s.Handle("GET /admin/moderation/chirps", apiConfig.requireRole(auth.RoleModerator, apiConfig.getModerationChirps))
+	s.Handle("POST /admin/moderation/chirps/{chirpID}/hide", apiConfig.requireRole(auth.RoleModerator, apiConfig.hideChirp))
+	s.Handle("POST /admin/moderation/chirps/{chirpID}/publish", apiConfig.requireRole(auth.RoleModerator, apiConfig.publishChirp))
+	s.Handle("POST /admin/moderation/users/{userID}/suspend", apiConfig.requireRole(auth.RoleAdmin, apiConfig.suspendUser))
+	s.Handle("POST /admin/moderation/users/{userID}/unsuspend", apiConfig.requireRole(auth.RoleAdmin, apiConfig.unsuspendUser))
+}
+
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "sexual", "misinformation", "other"}
+
+type Report struct {
+	ID             uuid.UUID  `json:"id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	ChirpID        uuid.UUID  `json:"chirp_id"`
+	ReporterID     uuid.UUID  `json:"reporter_id"`
+	Reason         string     `json:"reason"`
+	Details        string     `json:"details"`
+	Status         string     `json:"status"`
+	ResolutionNote *string    `json:"resolution_note"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+	Chirp          *Chirp     `json:"chirp,omitempty"`
+}
+
+type reportPage struct {
+	Reports    []Report `json:"reports"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+	PrevCursor string   `json:"prev_cursor,omitempty"`
+}
+
+func reportResponse(report database.Report) Report {
+	return Report{
+		ID:             report.ID,
+		CreatedAt:      report.CreatedAt,
+		UpdatedAt:      report.UpdatedAt,
+		ChirpID:        report.ChirpID,
+		ReporterID:     report.ReporterID,
+		Reason:         report.Reason,
+		Details:        report.Details,
+		Status:         report.Status,
+		ResolutionNote: nullableString(report.ResolutionNote),
+		ResolvedAt:     nullableTime(report.ResolvedAt),
+	}
+}
+
+func (cfg *ApiConfig) reportChirp(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	type reportRequest struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	params, err := util.DecodeJSON[reportRequest](r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	if !util.SliceContains(reportReasons, params.Reason) {
+		util.RespondWithError(w, http.StatusBadRequest, struct {
+			Error   string   `json:"error"`
+			Reasons []string `json:"reasons"`
+		}{Error: "unknown report reason", Reasons: reportReasons})
+		return
+	}
+
+	if len(params.Details) > 1000 {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "details are too long"})
+		return
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid chirp id"})
+		return
+	}
+
+	chirp, err := cfg.DbQueries.GetChirpById(r.Context(), chirpID)
+	if err == sql.ErrNoRows || (err == nil && !chirpVisible(chirp, uuid.NullUUID{UUID: userID, Valid: true})) {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Chirp not found"})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	if chirp.UserID == userID {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "users cannot report their own chirps"})
+		return
+	}
+
+	report, err := cfg.DbQueries.CreateReport(r.Context(), database.CreateReportParams{
+		ChirpID:    chirp.ID,
+		ReporterID: userID,
+		Reason:     params.Reason,
+		Details:    params.Details,
+	})
+	if err == sql.ErrNoRows {
+		util.RespondWithError(w, http.StatusConflict, util.ResponseError{Error: "chirp already reported"})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusCreated, reportResponse(report))
+}
+
+// Lists reports oldest first, optionally filtered by ?status=open|dismissed|actioned
+func (cfg *ApiConfig) getReports(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	status := sql.NullString{}
+	if s := r.URL.Query().Get("status"); s != "" {
+		status = sql.NullString{String: s, Valid: true}
+	}
+
+	after := func(c *cursor, limit int32) ([]database.Report, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetReportsAfterCursor(r.Context(), database.GetReportsAfterCursorParams{
+			Status:          status,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+	before := func(c *cursor, limit int32) ([]database.Report, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetReportsBeforeCursor(r.Context(), database.GetReportsBeforeCursorParams{
+			Status:          status,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+
+	result, err := fetchPage(page, after, before, func(report database.Report) cursor {
+		return cursor{CreatedAt: report.CreatedAt, ID: report.ID}
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	chirpIDs := []uuid.UUID{}
+	for _, report := range result.Items {
+		chirpIDs = append(chirpIDs, report.ChirpID)
+	}
+	chirps, err := cfg.DbQueries.GetChirpsByIds(r.Context(), chirpIDs)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	reported := map[uuid.UUID]Chirp{}
+	for _, chirp := range chirps {
+		reported[chirp.ID] = chirpResponse(chirp)
+	}
+
+	reports := []Report{}
+	for _, report := range result.Items {
+		response := reportResponse(report)
+		if chirp, ok := reported[report.ChirpID]; ok {
+			response.Chirp = &chirp
+		}
+		reports = append(reports, response)
+	}
+
+	setLinkHeader(w, r, page, result)
+	util.RespondWithJSON(w, 200, reportPage{
+		Reports:    reports,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
+	})
+}
+
+func (cfg *ApiConfig) resolveReport(w http.ResponseWriter, r *http.Request) {
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid report id"})
+		return
+	}
+
+	type resolveRequest struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+		// Hide the reported chirp as part of actioning the report
+		HideChirp bool `json:"hide_chirp"`
+	}
+	params, err := util.DecodeJSON[resolveRequest](r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	if params.Status != "dismissed" && params.Status != "actioned" {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "status must be dismissed or actioned"})
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
+
+	report, err := queries.ResolveReport(r.Context(), database.ResolveReportParams{
+		Status:         params.Status,
+		ResolutionNote: sql.NullString{String: params.Note, Valid: params.Note != ""},
+		ID:             reportID,
+	})
+	if err == sql.ErrNoRows {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Report not found"})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	// Hidden together with the report, so neither change is kept alone
+	if params.Status == "actioned" && params.HideChirp {
+		_, err = changeChirpStatus(r.Context(), queries, report.ChirpID, chirpStatusHidden)
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	util.RespondWithJSON(w, 200, reportResponse(report))
+}
+
+// Lists chirps awaiting review (?status=held, the default) or hidden ones
+func (cfg *ApiConfig) getModerationChirps(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = chirpStatusHeld
+	}
+
+	after := func(c *cursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetChirpsByStatusAfterCursor(r.Context(), database.GetChirpsByStatusAfterCursorParams{
+			Status:          status,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+	before := func(c *cursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetChirpsByStatusBeforeCursor(r.Context(), database.GetChirpsByStatusBeforeCursorParams{
+			Status:          status,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+
+	// Moderators need to see bodies that chirpsResponse would redact
+	result, err := fetchPage(page, after, before, chirpCursor)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	responseChirps := []Chirp{}
+	for _, chirp := range result.Items {
+		responseChirps = append(responseChirps, chirpResponse(chirp))
+	}
+
+	setLinkHeader(w, r, page, result)
+	util.RespondWithJSON(w, 200, chirpPage{
+		Chirps:     responseChirps,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
+	})
+}
+
+func (cfg *ApiConfig) hideChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid chirp id"})
+		return
+	}
+
+	chirp, ok := cfg.setChirpStatus(w, r, chirpID, chirpStatusHidden)
+	if !ok {
+		return
+	}
+
+	util.RespondWithJSON(w, 200, chirpResponse(chirp))
+}
+
+// Approves a held chirp or restores a hidden one
+func (cfg *ApiConfig) publishChirp(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid chirp id"})
+		return
+	}
+
+	chirp, ok := cfg.setChirpStatus(w, r, chirpID, chirpStatusPublished)
+	if !ok {
+		return
+	}
+
+	util.RespondWithJSON(w, 200, chirpResponse(chirp))
+}
+
+func (cfg *ApiConfig) setChirpStatus(w http.ResponseWriter, r *http.Request, chirpID uuid.UUID, status string) (database.Chirp, bool) {
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if util.ErrorNotNil(err, w) {
+		return database.Chirp{}, false
+	}
+	defer tx.Rollback()
+
+	chirp, err := changeChirpStatus(r.Context(), cfg.DbQueries.WithTx(tx), chirpID, status)
+	if err == sql.ErrNoRows {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Chirp not found"})
+		return chirp, false
+	}
+	if util.ErrorNotNil(err, w) {
+		return chirp, false
+	}
+
+	err = tx.Commit()
+	if util.ErrorNotNil(err, w) {
+		return chirp, false
+	}
+
+	return chirp, true
+}
+
+// Chirps leaving or returning to published are announced as deleted or
+// created, or restored when they were out before. queries must be bound to
+// a transaction, the events are only published if it commits.
+func changeChirpStatus(ctx context.Context, queries *database.Queries, chirpID uuid.UUID, status string) (database.Chirp, error) {
+	previous, err := queries.GetChirpByIdForUpdate(ctx, chirpID)
+	if err != nil {
+		return previous, err
+	}
+
+	chirp, err := queries.SetChirpStatus(ctx, database.SetChirpStatusParams{
+		Status: status,
+		ID:     chirpID,
+	})
+	if err != nil {
+		return chirp, err
+	}
+
+	if status == chirpStatusHidden {
+		err = queries.ResolveChirpReports(ctx, chirpID)
+		if err != nil {
+			return chirp, err
+		}
+	}
+
+	wasPublished := previous.Status == chirpStatusPublished
+	isPublished := chirp.Status == chirpStatusPublished
+	if wasPublished != isPublished && !chirp.DeletedAt.Valid {
+		var event events.Event = events.ChirpDeleted{
+			ID:       chirp.ID,
+			UserID:   chirp.UserID,
+			Hashtags: chirpHashtags(chirp.Body),
+		}
+		if isPublished && previous.PublishedAt.Valid {
+			event = events.ChirpRestored(chirpCreatedEvent(chirp))
+		} else if isPublished {
+			event = chirpCreatedEvent(chirp)
+		}
+		err = events.Publish(ctx, queries, event)
+		if err != nil {
+			return chirp, err
+		}
+	}
+
+	return chirp, nil
+}
+
+func (cfg *ApiConfig) suspendUser(w http.ResponseWriter, r *http.Request) {
+	cfg.setSuspended(w, r, true)
+}
+
+func (cfg *ApiConfig) unsuspendUser(w http.ResponseWriter, r *http.Request) {
+	cfg.setSuspended(w, r, false)
+}
+
+func (cfg *ApiConfig) setSuspended(w http.ResponseWriter, r *http.Request, suspended bool) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid user id"})
+		return
+	}
+
+	_, err = cfg.DbQueries.GetUserById(r.Context(), userID)
+	if err == sql.ErrNoRows {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "User not found"})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	if suspended {
+		err = cfg.DbQueries.SuspendUser(r.Context(), userID)
+	} else {
+		err = cfg.DbQueries.UnsuspendUser(r.Context(), userID)
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/notifications"
+	"chirpy/util"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func NotificationRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("GET /api/notifications", http.HandlerFunc(apiConfig.getNotifications))
+	s.Handle("POST /api/notifications/read", http.HandlerFunc(apiConfig.markAllNotificationsRead))
+	s.Handle("POST /api/notifications/{notificationID}/read", http.HandlerFunc(apiConfig.markNotificationRead))
+	s.Handle("GET /api/notifications/preferences", http.HandlerFunc(apiConfig.getNotificationPreferences))
+	s.Handle("PUT /api/notifications/preferences", http.HandlerFunc(apiConfig.updateNotificationPreferences))
+}
+
+type Notification struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Type        string    `json:"type"`
+	ActorID     uuid.UUID `json:"actor_id"`
+	ActorHandle *string   `json:"actor_handle"`
+	// The reply or mentioning chirp, or the chirp liked or rechirped
+	ChirpID uuid.UUID `json:"chirp_id"`
+	Read    bool      `json:"read"`
+}
+
+// The rows of both cursor queries have the same fields, so either converts
+// to the other
+func notificationResponse(row database.GetNotificationsAfterCursorRow) Notification {
+	return Notification{
+		ID:          row.ID,
+		CreatedAt:   row.CreatedAt,
+		Type:        row.Type,
+		ActorID:     row.ActorID,
+		ActorHandle: nullableString(row.ActorHandle),
+		ChirpID:     row.ChirpID,
+		Read:        row.ReadAt.Valid,
+	}
+}
+
+// Lists the caller's notifications with the number still unread, newest
+// first unless ?sort=asc. ?unread=true leaves out those already read.
+func (cfg *ApiConfig) getNotifications(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+	page.Desc = r.URL.Query().Get("sort") != "asc"
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	after := func(c *cursor, limit int32) ([]Notification, error) {
+		createdAt, id := c.params()
+		rows, err := cfg.DbQueries.GetNotificationsAfterCursor(r.Context(), database.GetNotificationsAfterCursorParams{
+			UserID:          userID,
+			UnreadOnly:      unreadOnly,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+		items := []Notification{}
+		for _, row := range rows {
+			items = append(items, notificationResponse(row))
+		}
+		return items, err
+	}
+	before := func(c *cursor, limit int32) ([]Notification, error) {
+		createdAt, id := c.params()
+		rows, err := cfg.DbQueries.GetNotificationsBeforeCursor(r.Context(), database.GetNotificationsBeforeCursorParams{
+			UserID:          userID,
+			UnreadOnly:      unreadOnly,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+		items := []Notification{}
+		for _, row := range rows {
+			items = append(items, notificationResponse(database.GetNotificationsAfterCursorRow(row)))
+		}
+		return items, err
+	}
+
+	result, err := fetchPage(page, after, before, func(n Notification) cursor {
+		return cursor{CreatedAt: n.CreatedAt, ID: n.ID}
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	unread, err := cfg.DbQueries.CountUnreadNotifications(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	setLinkHeader(w, r, page, result)
+	util.RespondWithJSON(w, 200, struct {
+		Notifications []Notification `json:"notifications"`
+		UnreadCount   int64          `json:"unread_count"`
+		NextCursor    string         `json:"next_cursor,omitempty"`
+		PrevCursor    string         `json:"prev_cursor,omitempty"`
+	}{
+		Notifications: result.Items,
+		UnreadCount:   unread,
+		NextCursor:    result.NextCursor,
+		PrevCursor:    result.PrevCursor,
+	})
+}
+
+func (cfg *ApiConfig) markNotificationRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	notificationID, err := uuid.Parse(r.PathValue("notificationID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid notification id"})
+		return
+	}
+
+	marked, err := cfg.DbQueries.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{
+		ID:     notificationID,
+		UserID: userID,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if marked == 0 {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Notification not found"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) markAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	err = cfg.DbQueries.MarkAllNotificationsRead(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Whether each notification type is on, keyed by type
+type notificationPreferences map[string]bool
+
+func notificationPreferencesResponse(user database.User) notificationPreferences {
+	preferences := notificationPreferences{}
+	for _, notificationType := range notifications.Types {
+		preferences[notificationType] = !util.SliceContains(user.MutedNotifications, notificationType)
+	}
+	return preferences
+}
+
+func (cfg *ApiConfig) getNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserById(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	util.RespondWithJSON(w, 200, notificationPreferencesResponse(user))
+}
+
+// Turns the types given on or off, leaving the others as they are
+func (cfg *ApiConfig) updateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	params, err := util.DecodeJSON[notificationPreferences](r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+	for notificationType := range params {
+		if !util.SliceContains(notifications.Types, notificationType) {
+			util.RespondWithError(w, http.StatusBadRequest, struct {
+				Error string   `json:"error"`
+				Types []string `json:"types"`
+			}{Error: "unknown notification type " + notificationType, Types: notifications.Types})
+			return
+		}
+	}
+
+	user, err := cfg.DbQueries.GetUserById(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	muted := []string{}
+	for _, notificationType := range notifications.Types {
+		enabled, ok := params[notificationType]
+		if !ok {
+			enabled = !util.SliceContains(user.MutedNotifications, notificationType)
+		}
+		if !enabled {
+			muted = append(muted, notificationType)
+		}
+	}
+
+	err = cfg.DbQueries.SetMutedNotifications(r.Context(), database.SetMutedNotificationsParams{
+		ID:                 userID,
+		MutedNotifications: muted,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	user.MutedNotifications = muted
+	util.RespondWithJSON(w, 200, notificationPreferencesResponse(user))
+}
@@ -0,0 +1,715 @@
+package handlers
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/util"
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// An OAuth 2.0 authorization server (RFC 6749) for third-party clients,
+// supporting the authorization code grant with PKCE (RFC 7636) only.
+// Clients are registered by users with their own JWT.
+func OAuthRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("POST /api/oauth/clients", http.HandlerFunc(apiConfig.createOAuthClient))
+	s.Handle("GET /api/oauth/clients", http.HandlerFunc(apiConfig.getOAuthClients))
+	s.Handle("DELETE /api/oauth/clients/{clientID}", http.HandlerFunc(apiConfig.deleteOAuthClient))
+	s.Handle("GET /oauth/authorize", http.HandlerFunc(apiConfig.authorize))
+	s.Handle("POST /oauth/authorize", http.HandlerFunc(apiConfig.approveAuthorization))
+	s.Handle("POST /oauth/token", http.HandlerFunc(apiConfig.oauthToken))
+	s.Handle("POST /oauth/introspect", http.HandlerFunc(apiConfig.introspect))
+	s.Handle("POST /oauth/revoke", http.HandlerFunc(apiConfig.oauthRevoke))
+}
+
+const (
+	oauthCodeLifetime        = 10 * time.Minute
+	oauthAccessTokenLifetime = time.Hour
+)
+
+// Shown on the consent page
+var scopeDescriptions = map[string]string{
+	auth.ScopeChirpsRead:   "Read chirps, including ones only visible to you",
+	auth.ScopeChirpsWrite:  "Post and delete chirps as you",
+	auth.ScopeProfileWrite: "Change your handle, but not your email or password",
+	auth.ScopeWebhooks:     "Manage your webhook subscriptions and see what was sent to them",
+}
+
+type OAuthClient struct {
+	ID           uuid.UUID `json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	// Only returned when a confidential client is registered
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+func oauthClientResponse(client database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID:           client.ID,
+		CreatedAt:    client.CreatedAt,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Scopes:       client.Scopes,
+		Confidential: client.SecretHash.Valid,
+	}
+}
+
+// Redirect URIs must be absolute and are compared exactly, so they may not
+// carry a fragment
+func validRedirectURI(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" || u.Fragment != "" {
+		return false
+	}
+	if u.Scheme == "https" {
+		return true
+	}
+	// Plain http is only allowed for apps listening on the user's machine
+	host := u.Hostname()
+	return u.Scheme == "http" && (host == "localhost" || host == "127.0.0.1" || host == "::1")
+}
+
+func (cfg *ApiConfig) createOAuthClient(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	type createClientRequest struct {
+		Name         string   `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Scopes       []string `json:"scopes"`
+		// Confidential clients authenticate with a secret; public clients,
+		// such as mobile apps, rely on PKCE alone
+		Confidential bool `json:"confidential"`
+	}
+	params, err := util.DecodeJSON[createClientRequest](r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	if params.Name == "" || len(params.Name) > 100 {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "name must be 1-100 characters"})
+		return
+	}
+
+	if len(params.RedirectURIs) == 0 {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "at least one redirect uri is required"})
+		return
+	}
+	for _, redirectURI := range params.RedirectURIs {
+		if !validRedirectURI(redirectURI) {
+			util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid redirect uri " + redirectURI})
+			return
+		}
+	}
+
+	err = auth.ValidateScopes(params.Scopes)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, struct {
+			Error  string   `json:"error"`
+			Scopes []string `json:"scopes"`
+		}{Error: err.Error(), Scopes: auth.Scopes})
+		return
+	}
+
+	secret := ""
+	secretHash := sql.NullString{}
+	if params.Confidential {
+		secret, err = auth.MakeRefreshToken()
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashAccessToken(secret), Valid: true}
+	}
+
+	client, err := cfg.DbQueries.CreateOAuthClient(r.Context(), database.CreateOAuthClientParams{
+		OwnerID:      userID,
+		Name:         params.Name,
+		RedirectUris: params.RedirectURIs,
+		Scopes:       params.Scopes,
+		SecretHash:   secretHash,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	response := oauthClientResponse(client)
+	response.ClientSecret = secret
+	util.RespondWithJSON(w, http.StatusCreated, response)
+}
+
+func (cfg *ApiConfig) getOAuthClients(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	clients, err := cfg.DbQueries.GetUserOAuthClients(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	response := []OAuthClient{}
+	for _, client := range clients {
+		response = append(response, oauthClientResponse(client))
+	}
+
+	util.RespondWithJSON(w, 200, response)
+}
+
+// Deleting a client also ends every session it was granted
+func (cfg *ApiConfig) deleteOAuthClient(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid client id"})
+		return
+	}
+
+	deleted, err := cfg.DbQueries.DeleteOAuthClient(r.Context(), database.DeleteOAuthClientParams{
+		ID:      clientID,
+		OwnerID: userID,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if deleted == 0 {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Client not found"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// An error as described in RFC 6749 section 5.2
+type oauthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *oauthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+// A validated authorization request
+type authorizationRequest struct {
+	Client        database.OauthClient
+	RedirectURI   string
+	Scopes        []string
+	State         string
+	CodeChallenge string
+}
+
+// The parameters the consent form posts back
+var authorizationParams = []string{"client_id", "redirect_uri", "response_type", "scope", "state", "code_challenge", "code_challenge_method"}
+
+// Validates the client and redirect URI first, since errors can only be
+// sent back to a redirect URI registered by the client. Errors about the
+// rest of the request are returned as an *oauthError to redirect with.
+func (cfg *ApiConfig) parseAuthorizationRequest(ctx context.Context, values url.Values) (authorizationRequest, error) {
+	req := authorizationRequest{State: values.Get("state")}
+
+	clientID, err := uuid.Parse(values.Get("client_id"))
+	if err != nil {
+		return req, errInvalidClient
+	}
+	req.Client, err = cfg.DbQueries.GetOAuthClient(ctx, clientID)
+	if err == sql.ErrNoRows {
+		return req, errInvalidClient
+	}
+	if err != nil {
+		return req, err
+	}
+
+	req.RedirectURI = values.Get("redirect_uri")
+	if !slices.Contains(req.Client.RedirectUris, req.RedirectURI) {
+		return req, errInvalidRedirectURI
+	}
+
+	if values.Get("response_type") != "code" {
+		return req, &oauthError{Code: "unsupported_response_type", Description: "only the code response type is supported"}
+	}
+
+	if values.Get("code_challenge_method") != "S256" || !auth.ValidCodeChallenge(values.Get("code_challenge")) {
+		return req, &oauthError{Code: "invalid_request", Description: "an S256 PKCE code challenge is required"}
+	}
+	req.CodeChallenge = values.Get("code_challenge")
+
+	// Without a scope the client asks for everything it was registered for
+	req.Scopes = auth.ParseScope(values.Get("scope"))
+	if len(req.Scopes) == 0 {
+		req.Scopes = req.Client.Scopes
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(req.Client.Scopes, scope) {
+			return req, &oauthError{Code: "invalid_scope", Description: "scope " + scope + " is not allowed for this client"}
+		}
+	}
+
+	return req, nil
+}
+
+var (
+	errInvalidClient      = &oauthError{Code: "invalid_client", Description: "unknown client"}
+	errInvalidRedirectURI = &oauthError{Code: "invalid_request", Description: "redirect uri is not registered for this client"}
+)
+
+// Handles errors from parseAuthorizationRequest, reporting true when the
+// response has been written
+func respondAuthorizationError(w http.ResponseWriter, r *http.Request, req authorizationRequest, err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == errInvalidClient || err == errInvalidRedirectURI {
+		util.RespondWithError(w, http.StatusBadRequest, err)
+		return true
+	}
+	if oauthErr, ok := err.(*oauthError); ok {
+		redirectToClient(w, r, req, url.Values{"error": {oauthErr.Code}, "error_description": {oauthErr.Description}})
+		return true
+	}
+	return util.ErrorNotNil(err, w)
+}
+
+// Sends the user agent back to the client, keeping any query the
+// registered redirect URI already has
+func redirectToClient(w http.ResponseWriter, r *http.Request, req authorizationRequest, params url.Values) {
+	u, _ := url.Parse(req.RedirectURI)
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if req.State != "" {
+		query.Set("state", req.State)
+	}
+	u.RawQuery = query.Encode()
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+type consentPageData struct {
+	ClientName string
+	Scopes     []string
+	Params     map[string]string
+	Email      string
+	Error      string
+}
+
+// Renders the page where users sign in and allow or deny the client. The
+// user's credentials are asked for on the page itself, so a forged form
+// submission cannot grant anything.
+func renderConsentPage(w http.ResponseWriter, status int, req authorizationRequest, values url.Values, email, message string) {
+	tmpl, err := template.ParseFiles("./oauth/authorize.html")
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	data := consentPageData{
+		ClientName: req.Client.Name,
+		Params:     map[string]string{},
+		Email:      email,
+		Error:      message,
+	}
+	for _, scope := range req.Scopes {
+		data.Scopes = append(data.Scopes, scopeDescriptions[scope])
+	}
+	for _, name := range authorizationParams {
+		data.Params[name] = values.Get(name)
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	tmpl.Execute(w, data)
+}
+
+func (cfg *ApiConfig) authorize(w http.ResponseWriter, r *http.Request) {
+	values := r.URL.Query()
+	req, err := cfg.parseAuthorizationRequest(r.Context(), values)
+	if respondAuthorizationError(w, r, req, err) {
+		return
+	}
+
+	renderConsentPage(w, 200, req, values, "", "")
+}
+
+// Receives the consent form. On approval the user signs in and the client
+// is sent an authorization code to exchange at /oauth/token.
+func (cfg *ApiConfig) approveAuthorization(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	values := r.PostForm
+	req, err := cfg.parseAuthorizationRequest(r.Context(), values)
+	if respondAuthorizationError(w, r, req, err) {
+		return
+	}
+
+	if values.Get("action") != "approve" {
+		redirectToClient(w, r, req, url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	// One attempt covers both the password and the two-factor code
+	email := values.Get("email")
+	decision, err := cfg.LoginThrottle.Attempt(r.Context(), r, email)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if !decision.Allowed() {
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfterSeconds(decision)))
+		renderConsentPage(w, http.StatusTooManyRequests, req, values, email, throttledMessage(decision))
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserByEmail(r.Context(), email)
+	if err != nil || auth.CheckPasswordHash(user.HashedPassword, values.Get("password")) != nil {
+		renderConsentPage(w, http.StatusUnauthorized, req, values, email, "Incorrect email or password")
+		return
+	}
+
+	if user.SuspendedAt.Valid {
+		renderConsentPage(w, http.StatusForbidden, req, values, email, "This account is suspended")
+		return
+	}
+
+	if user.TotpEnabledAt.Valid {
+		ok, err := cfg.checkSecondFactor(r.Context(), user, values.Get("code"))
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+		if !ok {
+			renderConsentPage(w, http.StatusUnauthorized, req, values, email, "Invalid two-factor code")
+			return
+		}
+	}
+
+	err = cfg.LoginThrottle.Release(r.Context(), r, email)
+	if err == nil {
+		err = cfg.LoginThrottle.Succeed(r.Context(), email)
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	code, err := auth.MakeRefreshToken()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = cfg.DbQueries.DeleteExpiredOAuthCodes(r.Context())
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = cfg.DbQueries.CreateOAuthCode(r.Context(), database.CreateOAuthCodeParams{
+		CodeHash:      auth.HashAccessToken(code),
+		ClientID:      req.Client.ID,
+		UserID:        user.ID,
+		RedirectUri:   req.RedirectURI,
+		Scopes:        req.Scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(oauthCodeLifetime),
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	redirectToClient(w, r, req, url.Values{"code": {code}})
+}
+
+// Token endpoint responses must never be cached (RFC 6749 section 5.1)
+func respondOAuthJSON(w http.ResponseWriter, code int, payload interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	util.RespondWithJSON(w, code, payload)
+}
+
+func respondOAuthError(w http.ResponseWriter, code int, oauthErr *oauthError) {
+	if oauthErr.Code == "invalid_client" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+	}
+	respondOAuthJSON(w, code, oauthErr)
+}
+
+// Identifies the calling client from HTTP Basic credentials or the
+// client_id and client_secret form fields. Public clients have no secret
+// and must not send one.
+func (cfg *ApiConfig) authenticateClient(r *http.Request) (database.OauthClient, *oauthError) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	invalid := &oauthError{Code: "invalid_client", Description: "client authentication failed"}
+	clientID, err := uuid.Parse(id)
+	if err != nil {
+		return database.OauthClient{}, invalid
+	}
+	client, err := cfg.DbQueries.GetOAuthClient(r.Context(), clientID)
+	if err != nil {
+		return database.OauthClient{}, invalid
+	}
+
+	if !client.SecretHash.Valid {
+		if secret != "" {
+			return database.OauthClient{}, invalid
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(auth.HashAccessToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		return database.OauthClient{}, invalid
+	}
+	return client, nil
+}
+
+var errInvalidGrant = &oauthError{Code: "invalid_grant", Description: "the grant is invalid, expired or was issued to another client"}
+
+func (cfg *ApiConfig) oauthToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		respondOAuthError(w, http.StatusBadRequest, &oauthError{Code: "invalid_request", Description: err.Error()})
+		return
+	}
+
+	client, oauthErr := cfg.authenticateClient(r)
+	if oauthErr != nil {
+		respondOAuthError(w, http.StatusUnauthorized, oauthErr)
+		return
+	}
+
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		cfg.exchangeAuthorizationCode(w, r, client)
+	case "refresh_token":
+		cfg.refreshClientToken(w, r, client)
+	default:
+		respondOAuthError(w, http.StatusBadRequest, &oauthError{Code: "unsupported_grant_type"})
+	}
+}
+
+// Responds with the tokens of a client session
+func (cfg *ApiConfig) respondClientTokens(w http.ResponseWriter, userID, sessionID uuid.UUID, client database.OauthClient, scopes []string, refreshToken string) {
+	accessToken, err := auth.MakeOAuthJWT(userID, sessionID, client.ID, scopes, cfg.Keys, oauthAccessTokenLifetime)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	respondOAuthJSON(w, 200, struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type"`
+		ExpiresIn    int    `json:"expires_in"`
+		RefreshToken string `json:"refresh_token"`
+		Scope        string `json:"scope"`
+	}{
+		AccessToken:  accessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(oauthAccessTokenLifetime.Seconds()),
+		RefreshToken: refreshToken,
+		Scope:        auth.FormatScope(scopes),
+	})
+}
+
+// Spends an authorization code, starting a session for the client
+func (cfg *ApiConfig) exchangeAuthorizationCode(w http.ResponseWriter, r *http.Request, client database.OauthClient) {
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
+
+	code, err := queries.UseOAuthCode(r.Context(), auth.HashAccessToken(r.PostForm.Get("code")))
+	if err == sql.ErrNoRows {
+		respondOAuthError(w, http.StatusBadRequest, errInvalidGrant)
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	if code.ClientID != client.ID || time.Now().After(code.ExpiresAt) || code.RedirectUri != r.PostForm.Get("redirect_uri") {
+		respondOAuthError(w, http.StatusBadRequest, errInvalidGrant)
+		return
+	}
+	if !auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+		respondOAuthError(w, http.StatusBadRequest, &oauthError{Code: "invalid_grant", Description: "code verifier does not match the challenge"})
+		return
+	}
+
+	user, err := queries.GetUserById(r.Context(), code.UserID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondOAuthError(w, http.StatusBadRequest, &oauthError{Code: "invalid_grant", Description: "account suspended"})
+		return
+	}
+
+	sessionID := uuid.New()
+	refreshToken, err := issueRefreshToken(r.Context(), queries, user.ID, sessionID, requestDevice(r, client.Name),
+		clientGrant{ClientID: uuid.NullUUID{UUID: client.ID, Valid: true}, Scopes: code.Scopes})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = tx.Commit()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	cfg.respondClientTokens(w, user.ID, sessionID, client, code.Scopes, refreshToken)
+}
+
+// Rotates a client's refresh token like /api/refresh does for users. The
+// session keeps the scopes originally granted.
+func (cfg *ApiConfig) refreshClientToken(w http.ResponseWriter, r *http.Request, client database.OauthClient) {
+	refreshToken, err := cfg.usableRefreshToken(r.Context(), r.PostForm.Get("refresh_token"))
+	if err == nil && (!refreshToken.ClientID.Valid || refreshToken.ClientID.UUID != client.ID) {
+		err = errRefreshTokenInvalid
+	}
+	if err == errRefreshTokenInvalid || err == errRefreshTokenExpired {
+		respondOAuthError(w, http.StatusBadRequest, errInvalidGrant)
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserById(r.Context(), refreshToken.UserID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondOAuthError(w, http.StatusBadRequest, &oauthError{Code: "invalid_grant", Description: "account suspended"})
+		return
+	}
+
+	newRefreshToken, err := cfg.rotateRefreshToken(r, refreshToken)
+	if err == errRefreshTokenReused {
+		respondOAuthError(w, http.StatusBadRequest, &oauthError{Code: "invalid_grant", Description: err.Error()})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	cfg.respondClientTokens(w, user.ID, refreshToken.FamilyID, client, refreshToken.Scopes, newRefreshToken)
+}
+
+// Token introspection (RFC 7662). Clients may only inspect tokens issued
+// to them; any other token is reported as inactive.
+func (cfg *ApiConfig) introspect(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		respondOAuthError(w, http.StatusBadRequest, &oauthError{Code: "invalid_request", Description: err.Error()})
+		return
+	}
+
+	client, oauthErr := cfg.authenticateClient(r)
+	if oauthErr != nil {
+		respondOAuthError(w, http.StatusUnauthorized, oauthErr)
+		return
+	}
+
+	type introspection struct {
+		Active    bool   `json:"active"`
+		Scope     string `json:"scope,omitempty"`
+		ClientID  string `json:"client_id,omitempty"`
+		Subject   string `json:"sub,omitempty"`
+		ExpiresAt int64  `json:"exp,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+	}
+
+	// Tokens of suspended users are reported inactive, as they are refused
+	// everywhere else
+	token := r.PostForm.Get("token")
+	if accessToken, err := cfg.validateAccessToken(r.Context(), token); err == nil {
+		if accessToken.ClientID.Valid && accessToken.ClientID.UUID == client.ID && cfg.checkNotSuspended(r.Context(), accessToken.UserID) == nil {
+			respondOAuthJSON(w, 200, introspection{
+				Active:    true,
+				Scope:     auth.FormatScope(accessToken.Scopes),
+				ClientID:  client.ID.String(),
+				Subject:   accessToken.UserID.String(),
+				ExpiresAt: accessToken.ExpiresAt.Unix(),
+				TokenType: "access_token",
+			})
+			return
+		}
+	} else if refreshToken, err := cfg.usableRefreshToken(r.Context(), token); err == nil {
+		if refreshToken.ClientID.Valid && refreshToken.ClientID.UUID == client.ID && cfg.checkNotSuspended(r.Context(), refreshToken.UserID) == nil {
+			respondOAuthJSON(w, 200, introspection{
+				Active:    true,
+				Scope:     auth.FormatScope(refreshToken.Scopes),
+				ClientID:  client.ID.String(),
+				Subject:   refreshToken.UserID.String(),
+				ExpiresAt: refreshToken.ExpiresAt.Unix(),
+				TokenType: "refresh_token",
+			})
+			return
+		}
+	}
+
+	respondOAuthJSON(w, 200, introspection{Active: false})
+}
+
+// Token revocation (RFC 7009). Revoking either token of a client ends
+// the whole session. Unknown tokens are not an error.
+func (cfg *ApiConfig) oauthRevoke(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		respondOAuthError(w, http.StatusBadRequest, &oauthError{Code: "invalid_request", Description: err.Error()})
+		return
+	}
+
+	client, oauthErr := cfg.authenticateClient(r)
+	if oauthErr != nil {
+		respondOAuthError(w, http.StatusUnauthorized, oauthErr)
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	sessionID := uuid.NullUUID{}
+	if refreshToken, err := cfg.DbQueries.GetRefreshToken(r.Context(), token); err == nil {
+		if refreshToken.ClientID.Valid && refreshToken.ClientID.UUID == client.ID {
+			sessionID = uuid.NullUUID{UUID: refreshToken.FamilyID, Valid: true}
+		}
+	} else if accessToken, err := auth.ParseJWT(token, cfg.Keys); err == nil {
+		if accessToken.ClientID.Valid && accessToken.ClientID.UUID == client.ID {
+			sessionID = accessToken.SessionID
+		}
+	}
+
+	if sessionID.Valid {
+		err = cfg.DbQueries.RevokeRefreshTokenFamily(r.Context(), sessionID.UUID)
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
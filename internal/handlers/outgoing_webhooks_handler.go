@@ -0,0 +1,378 @@
+package handlers
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/webhooks"
+	"chirpy/util"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Developers manage their own subscriptions under /api. Admins manage all
+// of them under /admin; the ones they make have no owner and get every
+// event, for Chirpy's own services.
+func OutgoingWebhookRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("POST /api/webhooks/subscriptions", apiConfig.ownWebhooks(apiConfig.createWebhookSubscription))
+	s.Handle("GET /api/webhooks/subscriptions", apiConfig.ownWebhooks(apiConfig.getWebhookSubscriptions))
+	s.Handle("DELETE /api/webhooks/subscriptions/{subscriptionID}", apiConfig.ownWebhooks(apiConfig.deleteWebhookSubscription))
+	s.Handle("GET /api/webhooks/subscriptions/{subscriptionID}/deliveries", apiConfig.ownWebhooks(apiConfig.getWebhookDeliveries))
+	s.Handle("GET /api/webhooks/deliveries/{deliveryID}", apiConfig.ownWebhooks(apiConfig.getWebhookDelivery))
+	s.Handle("POST /api/webhooks/deliveries/{deliveryID}/retry", apiConfig.ownWebhooks(apiConfig.retryWebhookDelivery))
+
+	s.Handle("POST /admin/webhooks/subscriptions", apiConfig.requireRole(auth.RoleAdmin, allWebhooks(apiConfig.createWebhookSubscription)))
+	s.Handle("GET /admin/webhooks/subscriptions", apiConfig.requireRole(auth.RoleAdmin, allWebhooks(apiConfig.getWebhookSubscriptions)))
+	s.Handle("DELETE /admin/webhooks/subscriptions/{subscriptionID}", apiConfig.requireRole(auth.RoleAdmin, allWebhooks(apiConfig.deleteWebhookSubscription)))
+	s.Handle("GET /admin/webhooks/subscriptions/{subscriptionID}/deliveries", apiConfig.requireRole(auth.RoleAdmin, allWebhooks(apiConfig.getWebhookDeliveries)))
+	s.Handle("GET /admin/webhooks/deliveries/{deliveryID}", apiConfig.requireRole(auth.RoleAdmin, allWebhooks(apiConfig.getWebhookDelivery)))
+	s.Handle("POST /admin/webhooks/deliveries/{deliveryID}/retry", apiConfig.requireRole(auth.RoleAdmin, allWebhooks(apiConfig.retryWebhookDelivery)))
+}
+
+// Handles a request about the subscriptions of owner, or every
+// subscription when owner is null
+type webhooksHandler func(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID)
+
+// Limits the handler to the caller's own subscriptions
+func (cfg *ApiConfig) ownWebhooks(next webhooksHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := cfg.authenticateScope(r, auth.ScopeWebhooks)
+		if err != nil {
+			respondAuthError(w, err)
+			return
+		}
+		next(w, r, uuid.NullUUID{UUID: userID, Valid: true})
+	}
+}
+
+func allWebhooks(next webhooksHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, r, uuid.NullUUID{})
+	}
+}
+
+type WebhookSubscription struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	// Only returned when the subscription is created
+	Secret string `json:"secret,omitempty"`
+}
+
+func webhookSubscriptionResponse(subscription database.WebhookSubscription) WebhookSubscription {
+	return WebhookSubscription{
+		ID:         subscription.ID,
+		CreatedAt:  subscription.CreatedAt,
+		URL:        subscription.Url,
+		EventTypes: subscription.EventTypes,
+	}
+}
+
+// A secret is generated when none is given
+func (cfg *ApiConfig) createWebhookSubscription(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) {
+	type createSubscriptionRequest struct {
+		URL        string   `json:"url"`
+		EventTypes []string `json:"event_types"`
+		Secret     string   `json:"secret"`
+	}
+	params, err := util.DecodeJSON[createSubscriptionRequest](r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	endpoint, err := url.Parse(params.URL)
+	if err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Host == "" {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "url must be an http or https URL"})
+		return
+	}
+	// Admins may point their subscriptions at Chirpy's own services
+	if owner.Valid {
+		err = webhooks.CheckEndpoint(r.Context(), endpoint)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+			return
+		}
+	}
+
+	if len(params.EventTypes) == 0 {
+		params.EventTypes = webhooks.EventTypes
+	}
+	for _, eventType := range params.EventTypes {
+		if !util.SliceContains(webhooks.EventTypes, eventType) {
+			util.RespondWithError(w, http.StatusBadRequest, struct {
+				Error      string   `json:"error"`
+				EventTypes []string `json:"event_types"`
+			}{Error: "unknown event type " + eventType, EventTypes: webhooks.EventTypes})
+			return
+		}
+	}
+
+	if params.Secret == "" {
+		secret := make([]byte, 32)
+		_, err = rand.Read(secret)
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+		params.Secret = hex.EncodeToString(secret)
+	}
+	if len(params.Secret) < 16 {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "secret must be at least 16 characters"})
+		return
+	}
+
+	subscription, err := cfg.DbQueries.CreateWebhookSubscription(r.Context(), database.CreateWebhookSubscriptionParams{
+		Url:        endpoint.String(),
+		EventTypes: params.EventTypes,
+		Secret:     params.Secret,
+		OwnerID:    owner,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	response := webhookSubscriptionResponse(subscription)
+	response.Secret = subscription.Secret
+	util.RespondWithJSON(w, http.StatusCreated, response)
+}
+
+func (cfg *ApiConfig) getWebhookSubscriptions(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) {
+	subscriptions, err := cfg.DbQueries.GetWebhookSubscriptions(r.Context(), owner)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	response := []WebhookSubscription{}
+	for _, subscription := range subscriptions {
+		response = append(response, webhookSubscriptionResponse(subscription))
+	}
+
+	util.RespondWithJSON(w, 200, response)
+}
+
+// Deletes the subscription along with its deliveries
+func (cfg *ApiConfig) deleteWebhookSubscription(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) {
+	subscriptionID, err := uuid.Parse(r.PathValue("subscriptionID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid subscription id"})
+		return
+	}
+
+	deleted, err := cfg.DbQueries.DeleteWebhookSubscription(r.Context(), database.DeleteWebhookSubscriptionParams{
+		ID:      subscriptionID,
+		OwnerID: owner,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if deleted == 0 {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Subscription not found"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	SubscriptionID uuid.UUID       `json:"subscription_id"`
+	EventID        uuid.UUID       `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	// When the next attempt is due, while the delivery is pending
+	NextAttemptAt *time.Time               `json:"next_attempt_at"`
+	DeliveredAt   *time.Time               `json:"delivered_at"`
+	AttemptLog    []WebhookDeliveryAttempt `json:"attempt_log,omitempty"`
+}
+
+type WebhookDeliveryAttempt struct {
+	CreatedAt      time.Time `json:"created_at"`
+	ResponseStatus *int32    `json:"response_status"`
+	Error          *string   `json:"error"`
+	DurationMs     int32     `json:"duration_ms"`
+}
+
+func webhookDeliveryResponse(delivery database.WebhookDelivery) WebhookDelivery {
+	response := WebhookDelivery{
+		ID:             delivery.ID,
+		CreatedAt:      delivery.CreatedAt,
+		UpdatedAt:      delivery.UpdatedAt,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Payload:        delivery.Payload,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		DeliveredAt:    nullableTime(delivery.DeliveredAt),
+	}
+	if delivery.Status == webhooks.StatusPending {
+		response.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	return response
+}
+
+// Lists a subscription's deliveries, filtered with ?status=dead and the like
+func (cfg *ApiConfig) getWebhookDeliveries(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) {
+	subscriptionID, err := uuid.Parse(r.PathValue("subscriptionID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid subscription id"})
+		return
+	}
+
+	_, err = cfg.DbQueries.GetWebhookSubscription(r.Context(), database.GetWebhookSubscriptionParams{
+		ID:      subscriptionID,
+		OwnerID: owner,
+	})
+	if err == sql.ErrNoRows {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Subscription not found"})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	status := sql.NullString{}
+	if s := r.URL.Query().Get("status"); s != "" {
+		status = sql.NullString{String: s, Valid: true}
+	}
+
+	after := func(c *cursor, limit int32) ([]database.WebhookDelivery, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetWebhookDeliveriesAfterCursor(r.Context(), database.GetWebhookDeliveriesAfterCursorParams{
+			SubscriptionID:  subscriptionID,
+			Status:          status,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+	before := func(c *cursor, limit int32) ([]database.WebhookDelivery, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetWebhookDeliveriesBeforeCursor(r.Context(), database.GetWebhookDeliveriesBeforeCursorParams{
+			SubscriptionID:  subscriptionID,
+			Status:          status,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+
+	result, err := fetchPage(page, after, before, func(delivery database.WebhookDelivery) cursor {
+		return cursor{CreatedAt: delivery.CreatedAt, ID: delivery.ID}
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	deliveries := []WebhookDelivery{}
+	for _, delivery := range result.Items {
+		deliveries = append(deliveries, webhookDeliveryResponse(delivery))
+	}
+
+	setLinkHeader(w, r, page, result)
+	util.RespondWithJSON(w, 200, struct {
+		Deliveries []WebhookDelivery `json:"deliveries"`
+		NextCursor string            `json:"next_cursor,omitempty"`
+		PrevCursor string            `json:"prev_cursor,omitempty"`
+	}{Deliveries: deliveries, NextCursor: result.NextCursor, PrevCursor: result.PrevCursor})
+}
+
+// Returns the delivery with every attempt made to send it
+func (cfg *ApiConfig) getWebhookDelivery(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) {
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid delivery id"})
+		return
+	}
+
+	delivery, err := cfg.DbQueries.GetWebhookDelivery(r.Context(), database.GetWebhookDeliveryParams{
+		ID:      deliveryID,
+		OwnerID: owner,
+	})
+	if err == sql.ErrNoRows {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Delivery not found"})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	attempts, err := cfg.DbQueries.GetWebhookDeliveryAttempts(r.Context(), deliveryID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	response := webhookDeliveryResponse(delivery)
+	response.AttemptLog = []WebhookDeliveryAttempt{}
+	for _, attempt := range attempts {
+		logged := WebhookDeliveryAttempt{
+			CreatedAt:  attempt.CreatedAt,
+			Error:      nullableString(attempt.Error),
+			DurationMs: attempt.DurationMs,
+		}
+		if attempt.ResponseStatus.Valid {
+			logged.ResponseStatus = &attempt.ResponseStatus.Int32
+		}
+		response.AttemptLog = append(response.AttemptLog, logged)
+	}
+
+	util.RespondWithJSON(w, 200, response)
+}
+
+// Queues a dead delivery again, e.g. once its endpoint is back up. It gets
+// one more attempt before it is dead again.
+func (cfg *ApiConfig) retryWebhookDelivery(w http.ResponseWriter, r *http.Request, owner uuid.NullUUID) {
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid delivery id"})
+		return
+	}
+
+	getDelivery := func() (database.WebhookDelivery, error) {
+		return cfg.DbQueries.GetWebhookDelivery(r.Context(), database.GetWebhookDeliveryParams{
+			ID:      deliveryID,
+			OwnerID: owner,
+		})
+	}
+	_, err = getDelivery()
+	if err == sql.ErrNoRows {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Delivery not found"})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	retried, err := cfg.DbQueries.RetryWebhookDelivery(r.Context(), deliveryID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if retried == 0 {
+		util.RespondWithError(w, http.StatusConflict, util.ResponseError{Error: "only dead deliveries can be retried"})
+		return
+	}
+
+	delivery, err := getDelivery()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	util.RespondWithJSON(w, 200, webhookDeliveryResponse(delivery))
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// Position of a row in a (created_at, id) keyset. Before marks a cursor
+// that asks for the page preceding the row rather than following it.
+type cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+	Before    bool      `json:"b,omitempty"`
+}
+
+func (c cursor) encode() string {
+	dat, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("invalid cursor")
+	}
+	if err := json.Unmarshal(dat, &c); err != nil || c.ID == uuid.Nil {
+		return c, errors.New("invalid cursor")
+	}
+	return c, nil
+}
+
+// Keyset values for the sqlc cursor parameters, NULL when there is no cursor
+func (c *cursor) params() (sql.NullTime, uuid.NullUUID) {
+	if c == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: c.CreatedAt, Valid: true}, uuid.NullUUID{UUID: c.ID, Valid: true}
+}
+
+type pageRequest struct {
+	Limit  int32
+	Cursor *cursor
+	Desc   bool
+}
+
+// Reads the limit, cursor and sort query parameters
+func parsePageRequest(r *http.Request) (pageRequest, error) {
+	query := r.URL.Query()
+	page := pageRequest{
+		Desc: query.Get("sort") == "desc",
+	}
+
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		return page, err
+	}
+	page.Limit = limit
+
+	if c := query.Get("cursor"); c != "" {
+		decoded, err := decodeCursor(c)
+		if err != nil {
+			return page, err
+		}
+		page.Cursor = &decoded
+	}
+
+	return page, nil
+}
+
+// Reads the limit query parameter, falling back to the default page size
+func parsePageLimit(r *http.Request) (int32, error) {
+	limit := r.URL.Query().Get("limit")
+	if limit == "" {
+		return defaultPageLimit, nil
+	}
+
+	n, err := strconv.Atoi(limit)
+	if err != nil || n < 1 || n > maxPageLimit {
+		return defaultPageLimit, errors.New("limit must be between 1 and " + strconv.Itoa(maxPageLimit))
+	}
+	return int32(n), nil
+}
+
+type pageResult[T any] struct {
+	Items      []T
+	NextCursor string
+	PrevCursor string
+}
+
+// keysetQuery fetches up to limit rows strictly after (ascending) or
+// before (descending) the cursor; a nil cursor starts from the respective end.
+type keysetQuery[T any] func(c *cursor, limit int32) ([]T, error)
+
+// Runs the keyset query matching the requested sort and direction and
+// returns the rows in display order along with the neighbouring cursors.
+func fetchPage[T any](page pageRequest, after, before keysetQuery[T], key func(T) cursor) (pageResult[T], error) {
+	forward := page.Cursor == nil || !page.Cursor.Before
+
+	query := after
+	if forward == page.Desc {
+		query = before
+	}
+
+	// Ask for one extra row to learn whether another page exists
+	rows, err := query(page.Cursor, page.Limit+1)
+	if err != nil {
+		return pageResult[T]{}, err
+	}
+	more := len(rows) > int(page.Limit)
+	if more {
+		rows = rows[:page.Limit]
+	}
+	if !forward {
+		slices.Reverse(rows)
+	}
+
+	result := pageResult[T]{Items: rows}
+	if len(rows) == 0 {
+		return result, nil
+	}
+
+	first := key(rows[0])
+	first.Before = true
+	last := key(rows[len(rows)-1])
+
+	if !forward || more {
+		result.NextCursor = last.encode()
+	}
+	if (forward && page.Cursor != nil) || (!forward && more) {
+		result.PrevCursor = first.encode()
+	}
+	return result, nil
+}
+
+// Sets an RFC 8288 Link header pointing at the neighbouring pages
+func setLinkHeader[T any](w http.ResponseWriter, r *http.Request, page pageRequest, result pageResult[T]) {
+	links := []string{}
+	for _, link := range []struct {
+		rel    string
+		cursor string
+	}{
+		{"next", result.NextCursor},
+		{"prev", result.PrevCursor},
+	} {
+		if link.cursor == "" {
+			continue
+		}
+		query := r.URL.Query()
+		query.Set("cursor", link.cursor)
+		query.Set("limit", strconv.Itoa(int(page.Limit)))
+		target := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		links = append(links, "<"+target.String()+`>; rel="`+link.rel+`"`)
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// Cursor for result sets that have no stable keyset, such as search
+// results ordered by relevance
+type offsetCursor struct {
+	Offset int32 `json:"o"`
+}
+
+func (c offsetCursor) encode() string {
+	dat, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(dat)
+}
+
+func decodeOffsetCursor(s string) (offsetCursor, error) {
+	var c offsetCursor
+	dat, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, errors.New("invalid cursor")
+	}
+	if err := json.Unmarshal(dat, &c); err != nil || c.Offset < 0 {
+		return c, errors.New("invalid cursor")
+	}
+	return c, nil
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"chirpy/internal/database"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestGetAllChirpsPages(t *testing.T) {
+	db := newFakeDB(t)
+	_, server := newTestServer(t, db, ChirpRoutes)
+
+	chirps := []database.Chirp{}
+	for i := range 5 {
+		chirps = append(chirps, testChirp(uuid.New(), i))
+	}
+	// Chirps posted at the same time are ordered by ID
+	chirps[3].CreatedAt = chirps[2].CreatedAt
+	if chirps[3].ID.String() < chirps[2].ID.String() {
+		chirps[2], chirps[3] = chirps[3], chirps[2]
+	}
+	ids := []uuid.UUID{}
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+
+	noChirpDetails(db)
+	db.on("GetChirpsAfterCursor", func(args []any) fakeResult {
+		return keysetChirps(chirps, false, args[2], args[3], args[4])
+	})
+	db.on("GetChirpsBeforeCursor", func(args []any) fakeResult {
+		return keysetChirps(chirps, true, args[2], args[3], args[4])
+	})
+
+	get := func(query string) (chirpPage, string) {
+		t.Helper()
+		w := serve(server, "GET", "/api/chirps?"+query, "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET /api/chirps?%s = %d %s, want 200", query, w.Code, w.Body)
+		}
+		var page chirpPage
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+		return page, w.Header().Get("Link")
+	}
+
+	// Forwards through every page, then back again
+	for _, sort := range []string{"asc", "desc"} {
+		t.Run(sort, func(t *testing.T) {
+			want := slices.Clone(ids)
+			if sort == "desc" {
+				slices.Reverse(want)
+			}
+			wantPages := [][]uuid.UUID{want[:2], want[2:4], want[4:]}
+
+			page, link := get("limit=2&sort=" + sort)
+			for i, wantPage := range wantPages {
+				if got := chirpIDs(page.Chirps); !slices.Equal(got, wantPage) {
+					t.Errorf("page %d = %v, want %v", i, got, wantPage)
+				}
+				if (page.PrevCursor != "") != (i > 0) {
+					t.Errorf("page %d previous cursor = %q", i, page.PrevCursor)
+				}
+				last := i == len(wantPages)-1
+				if (page.NextCursor != "") != !last {
+					t.Errorf("page %d next cursor = %q", i, page.NextCursor)
+				}
+				if !last && !strings.Contains(link, `cursor=`+url.QueryEscape(page.NextCursor)) {
+					t.Errorf("page %d Link header %q does not point at the next page", i, link)
+				}
+				if last {
+					break
+				}
+				page, link = get("limit=2&sort=" + sort + "&cursor=" + url.QueryEscape(page.NextCursor))
+			}
+
+			for i := len(wantPages) - 2; i >= 0; i-- {
+				page, _ = get("limit=2&sort=" + sort + "&cursor=" + url.QueryEscape(page.PrevCursor))
+				if got := chirpIDs(page.Chirps); !slices.Equal(got, wantPages[i]) {
+					t.Errorf("going back to page %d gave %v, want %v", i, got, wantPages[i])
+				}
+				if page.NextCursor == "" {
+					t.Errorf("page %d reached going back has no next cursor", i)
+				}
+			}
+			if page.PrevCursor != "" {
+				t.Errorf("the first page reached going back has previous cursor %q", page.PrevCursor)
+			}
+		})
+	}
+}
+
+func TestGetAllChirpsPageRequest(t *testing.T) {
+	author := uuid.New()
+	tests := []struct {
+		name      string
+		query     string
+		wantCode  int
+		wantLimit int32
+	}{
+		{"default limit", "", http.StatusOK, defaultPageLimit + 1},
+		{"limit", "limit=5", http.StatusOK, 6},
+		{"maximum limit", "limit=100", http.StatusOK, maxPageLimit + 1},
+		{"limit too large", "limit=101", http.StatusBadRequest, 0},
+		{"limit too small", "limit=0", http.StatusBadRequest, 0},
+		{"limit not a number", "limit=ten", http.StatusBadRequest, 0},
+		{"cursor not base64", "cursor=%25%25", http.StatusBadRequest, 0},
+		{"cursor without an id", "cursor=" + cursor{}.encode(), http.StatusBadRequest, 0},
+		{"author", "author_id=" + author.String(), http.StatusOK, defaultPageLimit + 1},
+		{"invalid author", "author_id=me", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t)
+			_, server := newTestServer(t, db, ChirpRoutes)
+			noChirpDetails(db)
+			db.on("GetChirpsAfterCursor", func(args []any) fakeResult {
+				if limit := args[4].(int32); limit != tt.wantLimit {
+					t.Errorf("asked for %d chirps, want %d", limit, tt.wantLimit)
+				}
+				wantAuthor := strings.Contains(tt.query, "author_id")
+				if filter := args[1].(uuid.NullUUID); filter.Valid != wantAuthor || (wantAuthor && filter.UUID != author) {
+					t.Errorf("author filter = %v", filter)
+				}
+				return rows()
+			})
+
+			w := serve(server, "GET", "/api/chirps?"+tt.query, "", nil)
+			if w.Code != tt.wantCode {
+				t.Errorf("GET /api/chirps?%s = %d %s, want %d", tt.query, w.Code, w.Body, tt.wantCode)
+			}
+			if tt.wantCode == http.StatusOK && len(db.called("GetChirpsAfterCursor")) != 1 {
+				t.Error("the page was not fetched")
+			}
+		})
+	}
+}
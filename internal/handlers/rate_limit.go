@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/ratelimit"
+	"chirpy/util"
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// How often clients may call each group of routes. Chirpy Red members get
+// RedMultiplier times the quota; a zero Burst leaves a group unlimited.
+type RateLimits struct {
+	Auth          ratelimit.Limit
+	Write         ratelimit.Limit
+	Read          ratelimit.Limit
+	RedMultiplier int
+}
+
+var DefaultRateLimits = RateLimits{
+	Auth:          ratelimit.PerMinute(10),
+	Write:         ratelimit.PerMinute(30),
+	Read:          ratelimit.PerMinute(300),
+	RedMultiplier: 5,
+}
+
+// Routes that sign users in or hand out tokens, limited hardest
+var authRoutes = []string{
+	"POST /api/users",
+	"POST /api/login",
+	"POST /api/login/2fa",
+	"POST /api/refresh",
+	"POST /api/revoke",
+	"POST /api/password/forgot",
+	"POST /api/password/reset",
+	"GET /api/users/verify",
+	"POST /api/users/verify/resend",
+	"POST /oauth/authorize",
+	"POST /oauth/token",
+	"POST /oauth/introspect",
+	"POST /oauth/revoke",
+}
+
+// Routes called by machines we trust to pace themselves
+var unlimitedRoutes = []string{
+	"GET /api/healthz",
+	"POST /api/polka/webhooks",
+}
+
+// Picks the group of the route the mux will dispatch to
+func (l RateLimits) route(method, pattern string) (string, ratelimit.Limit) {
+	switch {
+	case util.SliceContains(unlimitedRoutes, pattern):
+		return "", ratelimit.Limit{}
+	case util.SliceContains(authRoutes, pattern):
+		return "auth", l.Auth
+	case method == http.MethodGet || method == http.MethodHead:
+		return "read", l.Read
+	}
+	return "write", l.Write
+}
+
+// Limits requests before they reach the mux. Signed in users are limited
+// by user so their quota follows them across devices, anyone else by IP.
+func (cfg *ApiConfig) MiddlewareRateLimit(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.RateLimiter == nil {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		_, pattern := mux.Handler(r)
+		group, limit := cfg.RateLimits.route(r.Method, pattern)
+		if limit.Burst == 0 {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		client := "ip:" + requestDevice(r, "").IP
+		if userID, ok := cfg.rateLimitUser(r); ok {
+			client = "user:" + userID.String()
+			if cfg.redMember(r.Context(), userID) {
+				limit = limit.Scale(cfg.RateLimits.RedMultiplier)
+			}
+		}
+
+		result := cfg.RateLimiter.Allow(group+":"+client, limit)
+		w.Header().Set("RateLimit-Policy", limit.Policy())
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(int(math.Ceil(result.Reset.Seconds()))))
+
+		if !result.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
+			util.RespondWithError(w, http.StatusTooManyRequests, util.ResponseError{Error: "rate limit exceeded, slow down"})
+			return
+		}
+
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// Identifies the user behind the request's token without the session
+// checks of authenticate; an invalid token is rejected by the handler.
+// PATs need a lookup, otherwise minting more tokens would raise the quota.
+func (cfg *ApiConfig) rateLimitUser(r *http.Request) (uuid.UUID, bool) {
+	tokenString, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, false
+	}
+
+	if auth.IsPersonalAccessToken(tokenString) {
+		token, err := cfg.DbQueries.GetPersonalAccessTokenByHash(r.Context(), auth.HashAccessToken(tokenString))
+		if err != nil || token.RevokedAt.Valid {
+			return uuid.Nil, false
+		}
+		return token.UserID, true
+	}
+
+	token, err := auth.ParseJWT(tokenString, cfg.Keys)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return token.UserID, true
+}
+
+const membershipCacheTTL = time.Minute
+
+type cachedMembership struct {
+	red     bool
+	expires time.Time
+}
+
+// Remembers who is a Chirpy Red member for a while so limiting does not
+// cost a query per request
+type membershipCache struct {
+	mu      sync.Mutex
+	members map[uuid.UUID]cachedMembership
+}
+
+func (cfg *ApiConfig) redMember(ctx context.Context, userID uuid.UUID) bool {
+	cache := &cfg.memberships
+	now := time.Now()
+
+	cache.mu.Lock()
+	cached, ok := cache.members[userID]
+	cache.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.red
+	}
+
+	red, err := cfg.isChirpyRed(ctx, userID)
+	if err != nil {
+		return false
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if cache.members == nil || len(cache.members) > 100000 {
+		cache.members = map[uuid.UUID]cachedMembership{}
+	}
+	cache.members[userID] = cachedMembership{red: red, expires: now.Add(membershipCacheTTL)}
+	return red
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"chirpy/internal/database"
+	"chirpy/util"
+	"errors"
+	"html"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+type SearchResult struct {
+	Chirp
+	Snippet string  `json:"snippet"`
+	Rank    float32 `json:"rank"`
+}
+
+type searchPage struct {
+	Results    []SearchResult `json:"results"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	PrevCursor string         `json:"prev_cursor,omitempty"`
+}
+
+// Full-text search over chirp bodies, ordered by relevance.
+//
+// The q parameter accepts bare words (all must match), "quoted phrases",
+// prefix* matches, -excluded words and OR between terms.
+func (cfg *ApiConfig) searchChirps(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	tsQuery, err := buildTSQuery(query.Get("q"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+	page := pageRequest{Limit: limit}
+
+	offset := offsetCursor{}
+	if c := query.Get("cursor"); c != "" {
+		offset, err = decodeOffsetCursor(c)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+			return
+		}
+	}
+
+	author := uuid.NullUUID{}
+	if authorID := query.Get("author_id"); authorID != "" {
+		author.UUID, err = uuid.Parse(authorID)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid author_id"})
+			return
+		}
+		author.Valid = true
+	}
+
+	viewer := cfg.viewer(r)
+	rows, err := cfg.DbQueries.SearchChirps(r.Context(), database.SearchChirpsParams{
+		Query:      tsQuery,
+		ViewerID:   viewer,
+		AuthorID:   author,
+		PageLimit:  page.Limit + 1,
+		PageOffset: offset.Offset,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	more := len(rows) > int(page.Limit)
+	if more {
+		rows = rows[:page.Limit]
+	}
+
+	chirps := []database.Chirp{}
+	for _, row := range rows {
+		chirps = append(chirps, database.Chirp{
+			ID:        row.ID,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Body:      row.Body,
+			UserID:    row.UserID,
+			InReplyTo: row.InReplyTo,
+			DeletedAt: row.DeletedAt,
+			RechirpOf: row.RechirpOf,
+			Status:    row.Status,
+		})
+	}
+	responseChirps, err := cfg.chirpsResponse(r.Context(), chirps, viewer)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	results := []SearchResult{}
+	for i, row := range rows {
+		results = append(results, SearchResult{
+			Chirp:   responseChirps[i],
+			Snippet: highlightSnippet(row.Snippet),
+			Rank:    row.Rank,
+		})
+	}
+
+	result := pageResult[SearchResult]{Items: results}
+	if more {
+		result.NextCursor = offsetCursor{Offset: offset.Offset + page.Limit}.encode()
+	}
+	if offset.Offset > 0 {
+		result.PrevCursor = offsetCursor{Offset: max(0, offset.Offset-page.Limit)}.encode()
+	}
+
+	setLinkHeader(w, r, page, result)
+	util.RespondWithJSON(w, 200, searchPage{
+		Results:    result.Items,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
+	})
+}
+
+// Translates the user facing search syntax into a to_tsquery expression.
+// Only letters and digits make it into the expression so user input can
+// never produce a tsquery syntax error.
+func buildTSQuery(q string) (string, error) {
+	terms := []string{}
+	operator := " & "
+	pendingOr := false
+
+	addTerm := func(term string) {
+		if len(terms) > 0 {
+			if pendingOr {
+				terms = append(terms, " | ")
+			} else {
+				terms = append(terms, operator)
+			}
+		}
+		terms = append(terms, term)
+		pendingOr = false
+	}
+
+	for len(q) > 0 {
+		q = strings.TrimLeftFunc(q, unicode.IsSpace)
+		if q == "" {
+			break
+		}
+
+		// "quoted phrase"
+		if q[0] == '"' {
+			end := strings.IndexByte(q[1:], '"')
+			phrase := q[1:]
+			if end >= 0 {
+				phrase = q[1 : end+1]
+				q = q[end+2:]
+			} else {
+				q = ""
+			}
+			if words := searchWords(phrase); len(words) > 0 {
+				addTerm("(" + strings.Join(words, " <-> ") + ")")
+			}
+			continue
+		}
+
+		end := strings.IndexFunc(q, unicode.IsSpace)
+		token := q
+		if end >= 0 {
+			token, q = q[:end], q[end:]
+		} else {
+			q = ""
+		}
+
+		if token == "OR" {
+			pendingOr = len(terms) > 0
+			continue
+		}
+
+		negate := strings.HasPrefix(token, "-")
+		prefix := strings.HasSuffix(token, "*")
+		words := searchWords(token)
+		if len(words) == 0 {
+			continue
+		}
+		if prefix {
+			words[len(words)-1] += ":*"
+		}
+
+		term := strings.Join(words, " <-> ")
+		if len(words) > 1 {
+			term = "(" + term + ")"
+		}
+		if negate {
+			term = "!" + term
+		}
+		addTerm(term)
+	}
+
+	if len(terms) == 0 {
+		return "", errors.New("search query must contain at least one word")
+	}
+	return strings.Join(terms, ""), nil
+}
+
+// Splits text into the runs of letters and digits it contains
+func searchWords(text string) []string {
+	return strings.FieldsFunc(text, func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+}
+
+// Marks ts_headline puts around matches. SearchChirps removes them from
+// the body first, so they can only have come from ts_headline.
+const (
+	snippetStartSel = "\x02"
+	snippetStopSel  = "\x03"
+)
+
+// ts_headline does not escape the chirp body, so escape it here and turn
+// only its marks into <mark> tags
+func highlightSnippet(snippet string) string {
+	escaped := html.EscapeString(snippet)
+	escaped = strings.ReplaceAll(escaped, snippetStartSel, "<mark>")
+	return strings.ReplaceAll(escaped, snippetStopSel, "</mark>")
+}
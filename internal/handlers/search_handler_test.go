@@ -0,0 +1,21 @@
+package handlers
+
+import "testing"
+
+func TestHighlightSnippet(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet string
+		want    string
+	}{
+		{"marks", "the \x02gopher\x03 is here", "the <mark>gopher</mark> is here"},
+		{"escaped", "\x02a\x03 < b & \"c\"", "<mark>a</mark> &lt; b &amp; &#34;c&#34;"},
+		{"literal mark tags", "<mark>fake</mark> \x02real\x03", "&lt;mark&gt;fake&lt;/mark&gt; <mark>real</mark>"},
+		{"unbalanced tags", "</mark><script>", "&lt;/mark&gt;&lt;script&gt;"},
+	}
+	for _, tt := range tests {
+		if got := highlightSnippet(tt.snippet); got != tt.want {
+			t.Errorf("highlightSnippet(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
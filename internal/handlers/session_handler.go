@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"chirpy/internal/database"
+	"chirpy/util"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func SessionRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("GET /api/sessions", http.HandlerFunc(apiConfig.getSessions))
+	s.Handle("DELETE /api/sessions/{sessionID}", http.HandlerFunc(apiConfig.deleteSession))
+}
+
+// A signed in device. The session's ID is the family ID shared by every
+// refresh token rotated from the same login.
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	Label      string    `json:"label"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	StartedAt  time.Time `json:"started_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+	// Set when the session was granted to an OAuth client
+	ClientID *uuid.UUID `json:"client_id,omitempty"`
+}
+
+type sessionDevice struct {
+	UserAgent string
+	IP        string
+	Label     string
+}
+
+func requestDevice(r *http.Request, label string) sessionDevice {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	return sessionDevice{
+		UserAgent: r.UserAgent(),
+		IP:        ip,
+		Label:     label,
+	}
+}
+
+func (cfg *ApiConfig) getSessions(w http.ResponseWriter, r *http.Request) {
+	token, err := cfg.userToken(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	rows, err := cfg.DbQueries.GetUserSessions(r.Context(), token.UserID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	sessions := []Session{}
+	for _, row := range rows {
+		sessions = append(sessions, Session{
+			ID:         row.FamilyID,
+			Label:      row.Label,
+			UserAgent:  row.UserAgent,
+			IP:         row.Ip,
+			StartedAt:  row.StartedAt,
+			LastUsedAt: row.LastUsedAt,
+			ExpiresAt:  row.ExpiresAt,
+			Current:    token.SessionID.Valid && token.SessionID.UUID == row.FamilyID,
+			ClientID:   nullableUUID(row.ClientID),
+		})
+	}
+
+	util.RespondWithJSON(w, 200, sessions)
+}
+
+// Signs a device out. Its refresh tokens are revoked and access tokens
+// minted from them stop being accepted straight away.
+func (cfg *ApiConfig) deleteSession(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid session id"})
+		return
+	}
+
+	revoked, err := cfg.DbQueries.RevokeUserSession(r.Context(), database.RevokeUserSessionParams{
+		FamilyID: sessionID,
+		UserID:   userID,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if revoked == 0 {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Session not found"})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/entities"
+	"chirpy/internal/events"
+	"chirpy/util"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+)
+
+// Both streams push chirp.created, chirp.deleted and chirp.restored events
+// as they are committed, filtered with ?author_id=, ?hashtag= and ?following=true.
+// Clients resume after the last event they got with Last-Event-ID, or
+// ?last_event_id= where they cannot set headers.
+func StreamRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("GET /api/stream", http.HandlerFunc(apiConfig.streamEvents))
+	s.Handle("GET /api/stream/ws", http.HandlerFunc(apiConfig.streamWebSocket))
+	s.Handle("POST /api/stream/token", http.HandlerFunc(apiConfig.createStreamToken))
+}
+
+const (
+	// Keeps proxies from closing idle streams
+	streamHeartbeat    = 25 * time.Second
+	streamWriteTimeout = 10 * time.Second
+	// Stream tokens end up in URLs and logs, so they only last long enough
+	// to connect. Streams already open are not cut off when they expire.
+	streamTokenLifetime = time.Minute
+)
+
+// The client fell too far behind and was dropped by the hub
+var errStreamBehind = errors.New("stream fell behind, reconnect with the last event id")
+
+// Which chirp events a client wants. Every filter given must match.
+type streamFilter struct {
+	author  uuid.NullUUID
+	hashtag string
+	// Nil unless the client asked for the users it follows. Loaded when
+	// the stream starts, follows made later need a reconnect.
+	following map[uuid.UUID]bool
+}
+
+func (cfg *ApiConfig) parseStreamFilter(r *http.Request) (streamFilter, int, error) {
+	query := r.URL.Query()
+	filter := streamFilter{}
+
+	if authorID := query.Get("author_id"); authorID != "" {
+		id, err := uuid.Parse(authorID)
+		if err != nil {
+			return filter, http.StatusBadRequest, errors.New("invalid author_id")
+		}
+		filter.author = uuid.NullUUID{UUID: id, Valid: true}
+	}
+
+	if hashtag := query.Get("hashtag"); hashtag != "" {
+		filter.hashtag = entities.Fold(strings.TrimPrefix(hashtag, "#"))
+	}
+
+	if query.Get("following") == "true" {
+		userID, err := cfg.streamUser(r)
+		if err == errInsufficientScope || err == errAccountSuspended {
+			return filter, http.StatusForbidden, err
+		}
+		if err != nil {
+			return filter, http.StatusUnauthorized, err
+		}
+
+		followees, err := cfg.DbQueries.GetFolloweeIDs(r.Context(), userID)
+		if err != nil {
+			return filter, http.StatusInternalServerError, err
+		}
+		filter.following = map[uuid.UUID]bool{}
+		for _, id := range followees {
+			filter.following[id] = true
+		}
+	}
+
+	return filter, 0, nil
+}
+
+// Browsers cannot set headers on EventSource or WebSocket connections, so
+// besides the Authorization header a token from POST /api/stream/token is
+// accepted as ?token=
+func (cfg *ApiConfig) streamUser(r *http.Request) (uuid.UUID, error) {
+	tokenString := r.URL.Query().Get("token")
+	if tokenString == "" {
+		return cfg.authenticateScope(r, auth.ScopeChirpsRead)
+	}
+
+	token, err := auth.ValidatePurposeJWT(tokenString, auth.PurposeStream, cfg.Keys)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid or expired stream token")
+	}
+	return token.UserID, cfg.checkNotSuspended(r.Context(), token.UserID)
+}
+
+// Issues a token for ?following=true streams opened from a browser. It
+// has to be fetched again before reconnecting once it has expired.
+func (cfg *ApiConfig) createStreamToken(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticateScope(r, auth.ScopeChirpsRead)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	token, err := auth.MakePurposeJWT(userID, auth.PurposeStream, "", cfg.Keys, streamTokenLifetime)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	util.RespondWithJSON(w, 201, struct {
+		Token     string `json:"token"`
+		ExpiresIn int    `json:"expires_in"`
+	}{Token: token, ExpiresIn: int(streamTokenLifetime.Seconds())})
+}
+
+func (f streamFilter) match(event events.Envelope) bool {
+	var author uuid.UUID
+	var hashtags []string
+	switch e := event.Event.(type) {
+	case events.ChirpCreated:
+		author, hashtags = e.UserID, e.Hashtags
+	case events.ChirpDeleted:
+		author, hashtags = e.UserID, e.Hashtags
+	case events.ChirpRestored:
+		author, hashtags = e.UserID, e.Hashtags
+	default:
+		return false
+	}
+
+	if f.author.Valid && f.author.UUID != author {
+		return false
+	}
+	if f.hashtag != "" && !util.SliceContains(hashtags, f.hashtag) {
+		return false
+	}
+	if f.following != nil && !f.following[author] {
+		return false
+	}
+	return true
+}
+
+// Where a reconnecting client left off, if it says
+func lastEventID(r *http.Request) (*events.Position, error) {
+	id := r.Header.Get("Last-Event-ID")
+	if id == "" {
+		id = r.URL.Query().Get("last_event_id")
+	}
+	if id == "" {
+		return nil, nil
+	}
+
+	position, err := events.ParsePosition(id)
+	if err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
+
+// Feeds a client until ctx is done or a write fails: first the events it
+// missed since resume, then live ones. Subscribing before reading the
+// backlog means nothing is lost in between; live events the backlog
+// already covered are skipped.
+func (cfg *ApiConfig) streamChirps(ctx context.Context, filter streamFilter, resume *events.Position, send func(events.Envelope) error, ping func() error) error {
+	sub := cfg.Stream.Subscribe()
+	defer cfg.Stream.Unsubscribe(sub)
+
+	var position events.Position
+	if resume != nil {
+		position = *resume
+		for {
+			backlog, err := cfg.Stream.Backlog(ctx, position)
+			if err != nil {
+				return err
+			}
+			if len(backlog) == 0 {
+				break
+			}
+			for _, event := range backlog {
+				position = event.Position
+				if !filter.match(event) {
+					continue
+				}
+				if err := send(event); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-heartbeat.C:
+			if err := ping(); err != nil {
+				return err
+			}
+		case event, ok := <-sub.C:
+			if !ok {
+				return errStreamBehind
+			}
+			if resume != nil && !event.Position.After(position) {
+				continue
+			}
+			position = event.Position
+			if !filter.match(event) {
+				continue
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Server-Sent Events. Each event's id is its position, so browsers resume
+// where they left off on their own.
+func (cfg *ApiConfig) streamEvents(w http.ResponseWriter, r *http.Request) {
+	filter, code, err := cfg.parseStreamFilter(r)
+	if err != nil {
+		util.RespondWithError(w, code, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	resume, err := lastEventID(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Stops nginx buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	send := func(event events.Envelope) error {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.Position, event.Type, event.Payload)
+		if err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	ping := func() error {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	cfg.streamChirps(r.Context(), filter, resume, send, ping)
+}
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+type streamMessage struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// The same stream over a WebSocket, one JSON message per event. Messages
+// from the client are ignored.
+func (cfg *ApiConfig) streamWebSocket(w http.ResponseWriter, r *http.Request) {
+	filter, code, err := cfg.parseStreamFilter(r)
+	if err != nil {
+		util.RespondWithError(w, code, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	resume, err := lastEventID(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	// Upgrade answers failed handshakes itself
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// The request's context outlives the hijacked connection, so reading
+	// is what notices the client going away
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(event events.Envelope) error {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return conn.WriteJSON(streamMessage{ID: event.Position.String(), Type: event.Type, Data: event.Payload})
+	}
+	ping := func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout))
+	}
+
+	err = cfg.streamChirps(ctx, filter, resume, send, ping)
+	closeCode, reason := websocket.CloseNormalClosure, ""
+	if err == errStreamBehind {
+		closeCode, reason = websocket.CloseTryAgainLater, err.Error()
+	} else if err != nil {
+		closeCode = websocket.CloseInternalServerErr
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, reason), time.Now().Add(time.Second))
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultSubscriptionPlan = "chirpy_red"
+	// How long members keep Chirpy Red after their period ends without a
+	// renewal, giving Polka time to retry a failed payment
+	subscriptionGracePeriod = 3 * 24 * time.Hour
+)
+
+// Subscription states
+const (
+	subscriptionActive   = "active"
+	subscriptionPastDue  = "past_due"
+	subscriptionCanceled = "canceled"
+	subscriptionRefunded = "refunded"
+)
+
+// Polka events about Chirpy Red
+const (
+	polkaUserUpgraded        = "user.upgraded"
+	polkaUserDowngraded      = "user.downgraded"
+	polkaSubscriptionRenewed = "subscription.renewed"
+	polkaPaymentFailed       = "payment.failed"
+	polkaPaymentRefunded     = "payment.refunded"
+)
+
+type polkaEvent struct {
+	// Unique per event, repeated when Polka retries a delivery
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  struct {
+		UserID string `json:"user_id"`
+		Plan   string `json:"plan"`
+		// The billing period paid for, sent with upgrades and renewals.
+		// Periods default to a month from the previous one.
+		PeriodStart *time.Time `json:"period_start"`
+		PeriodEnd   *time.Time `json:"period_end"`
+	} `json:"data"`
+}
+
+var errUnknownUser = errors.New("user not found")
+
+// Whether the user is a Chirpy Red member: their subscription is active or
+// past due, and its period has not ended more than the grace period ago.
+// Expiry needs no job, it follows from the period's end.
+func (cfg *ApiConfig) isChirpyRed(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return cfg.DbQueries.HasActiveSubscription(ctx, database.HasActiveSubscriptionParams{
+		UserID:      userID,
+		GraceCutoff: time.Now().Add(-subscriptionGracePeriod),
+	})
+}
+
+// Updates the subscription of the event's user. Events the subscription
+// cannot follow, such as a failed payment without a subscription, and
+// unknown events are ignored.
+func applyPolkaEvent(ctx context.Context, queries *database.Queries, event polkaEvent) error {
+	userID, err := uuid.Parse(event.Data.UserID)
+	if err != nil {
+		return errUnknownUser
+	}
+
+	_, err = queries.GetUserById(ctx, userID)
+	if err == sql.ErrNoRows {
+		return errUnknownUser
+	}
+	if err != nil {
+		return err
+	}
+
+	switch event.Event {
+	case polkaUserUpgraded:
+		return startSubscription(ctx, queries, userID, event)
+	case polkaSubscriptionRenewed:
+		return renewSubscription(ctx, queries, userID, event)
+	case polkaPaymentFailed:
+		_, err = queries.MarkSubscriptionPastDue(ctx, userID)
+	case polkaUserDowngraded:
+		_, err = queries.EndSubscription(ctx, database.EndSubscriptionParams{UserID: userID, Status: subscriptionCanceled})
+	case polkaPaymentRefunded:
+		_, err = queries.EndSubscription(ctx, database.EndSubscriptionParams{UserID: userID, Status: subscriptionRefunded})
+	}
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	return err
+}
+
+// The period an event pays for, starting at start unless Polka says
+// otherwise
+func eventPeriod(event polkaEvent, start time.Time) (time.Time, time.Time) {
+	if event.Data.PeriodStart != nil {
+		start = *event.Data.PeriodStart
+	}
+	end := start.AddDate(0, 1, 0)
+	if event.Data.PeriodEnd != nil {
+		end = *event.Data.PeriodEnd
+	}
+	return start, end
+}
+
+func startSubscription(ctx context.Context, queries *database.Queries, userID uuid.UUID, event polkaEvent) error {
+	plan := event.Data.Plan
+	if plan == "" {
+		plan = defaultSubscriptionPlan
+	}
+
+	start, end := eventPeriod(event, time.Now())
+	subscription, err := queries.StartSubscription(ctx, database.StartSubscriptionParams{
+		UserID:             userID,
+		Plan:               plan,
+		CurrentPeriodStart: start,
+		CurrentPeriodEnd:   end,
+	})
+	if err != nil {
+		return err
+	}
+
+	return events.Publish(ctx, queries, events.UserUpgraded{
+		UserID:           userID,
+		Plan:             subscription.Plan,
+		CurrentPeriodEnd: subscription.CurrentPeriodEnd,
+	})
+}
+
+// Starts the next period where the current one ends, or now if it lapsed
+func renewSubscription(ctx context.Context, queries *database.Queries, userID uuid.UUID, event polkaEvent) error {
+	subscription, err := queries.GetSubscription(ctx, userID)
+	if err == sql.ErrNoRows {
+		return startSubscription(ctx, queries, userID, event)
+	}
+	if err != nil {
+		return err
+	}
+
+	start := subscription.CurrentPeriodEnd
+	if start.Before(time.Now()) || subscription.EndedAt.Valid {
+		start = time.Now()
+	}
+	start, end := eventPeriod(event, start)
+
+	_, err = queries.RenewSubscription(ctx, database.RenewSubscriptionParams{
+		UserID:             userID,
+		CurrentPeriodStart: start,
+		CurrentPeriodEnd:   end,
+	})
+	return err
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"chirpy/internal/database"
+	"chirpy/util"
+	"database/sql"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+func ThreadRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("GET /api/chirps/{chirpID}/thread", http.HandlerFunc(apiConfig.getThread))
+}
+
+type threadResponse struct {
+	Chirp      Chirp   `json:"chirp"`
+	Ancestors  []Chirp `json:"ancestors"`
+	Replies    []Chirp `json:"replies"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+}
+
+// Returns the chain of ancestors from the root of the conversation down to
+// the chirp, followed by a page of every reply beneath it. Deleted chirps
+// are kept as tombstones so the shape of the thread is preserved.
+func (cfg *ApiConfig) getThread(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid chirp id"})
+		return
+	}
+
+	page, err := parsePageRequest(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	viewer := cfg.viewer(r)
+	chirp, err := cfg.DbQueries.GetChirpById(r.Context(), chirpID)
+	if err == sql.ErrNoRows || (err == nil && !chirp.DeletedAt.Valid && !chirpVisible(chirp, viewer)) {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Chirp not found"})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	ancestorRows, err := cfg.DbQueries.GetChirpAncestors(r.Context(), chirpID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	thread := []database.Chirp{}
+	for _, row := range ancestorRows {
+		thread = append(thread, database.Chirp(row))
+	}
+	thread = append(thread, chirp)
+
+	after := func(c *cursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := c.params()
+		rows, err := cfg.DbQueries.GetChirpDescendantsAfterCursor(r.Context(), database.GetChirpDescendantsAfterCursorParams{
+			RootID:          chirpID,
+			ViewerID:        viewer,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+		replies := []database.Chirp{}
+		for _, row := range rows {
+			replies = append(replies, database.Chirp(row))
+		}
+		return replies, err
+	}
+	before := func(c *cursor, limit int32) ([]database.Chirp, error) {
+		createdAt, id := c.params()
+		rows, err := cfg.DbQueries.GetChirpDescendantsBeforeCursor(r.Context(), database.GetChirpDescendantsBeforeCursorParams{
+			RootID:          chirpID,
+			ViewerID:        viewer,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+		replies := []database.Chirp{}
+		for _, row := range rows {
+			replies = append(replies, database.Chirp(row))
+		}
+		return replies, err
+	}
+
+	result, err := fetchPage(page, after, before, chirpCursor)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	threadChirps, err := cfg.chirpsResponse(r.Context(), thread, viewer)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	replies, err := cfg.chirpsResponse(r.Context(), result.Items, viewer)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	setLinkHeader(w, r, page, result)
+	util.RespondWithJSON(w, 200, threadResponse{
+		Chirp:      threadChirps[len(threadChirps)-1],
+		Ancestors:  threadChirps[:len(threadChirps)-1],
+		Replies:    replies,
+		NextCursor: result.NextCursor,
+		PrevCursor: result.PrevCursor,
+	})
+}
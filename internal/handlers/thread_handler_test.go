@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"chirpy/internal/database"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGetThread(t *testing.T) {
+	db := newFakeDB(t)
+	_, server := newTestServer(t, db, ThreadRoutes)
+
+	author := uuid.New()
+	root := testChirp(author, 0)
+	parent := testChirp(author, 1)
+	parent.InReplyTo = uuid.NullUUID{UUID: root.ID, Valid: true}
+	parent.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	parent.Body = ""
+	chirp := testChirp(author, 2)
+	chirp.InReplyTo = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	replies := []database.Chirp{}
+	for i := range 3 {
+		reply := testChirp(uuid.New(), 10+i)
+		reply.InReplyTo = uuid.NullUUID{UUID: chirp.ID, Valid: true}
+		replies = append(replies, reply)
+	}
+
+	chirpsByID(db, chirp)
+	noChirpDetails(db)
+	db.on("GetChirpAncestors", returns(rows(root, parent)))
+	db.on("GetChirpDescendantsAfterCursor", func(args []any) fakeResult {
+		if args[0].(uuid.NullUUID).Valid {
+			t.Errorf("anonymous thread asked for the replies of viewer %v", args[0])
+		}
+		if args[4].(uuid.UUID) != chirp.ID {
+			t.Errorf("asked for the replies of %v, want %v", args[4], chirp.ID)
+		}
+		return keysetChirps(replies, false, args[1], args[2], args[3])
+	})
+	db.on("GetChirpDescendantsBeforeCursor", func(args []any) fakeResult {
+		return keysetChirps(replies, true, args[1], args[2], args[3])
+	})
+
+	get := func(target string) threadResponse {
+		t.Helper()
+		w := serve(server, "GET", target, "", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s = %d %s, want 200", target, w.Code, w.Body)
+		}
+		var thread threadResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &thread); err != nil {
+			t.Fatal(err)
+		}
+		return thread
+	}
+
+	path := "/api/chirps/" + chirp.ID.String() + "/thread"
+	thread := get(path + "?limit=2")
+	if thread.Chirp.ID != chirp.ID {
+		t.Errorf("chirp = %v, want %v", thread.Chirp.ID, chirp.ID)
+	}
+	if len(thread.Ancestors) != 2 || thread.Ancestors[0].ID != root.ID || thread.Ancestors[1].ID != parent.ID {
+		t.Errorf("ancestors = %+v, want the root then the parent", thread.Ancestors)
+	} else if !thread.Ancestors[1].Deleted {
+		t.Error("the deleted parent is not a tombstone")
+	}
+	if ids := chirpIDs(thread.Replies); !slices.Equal(ids, []uuid.UUID{replies[0].ID, replies[1].ID}) {
+		t.Errorf("first page of replies = %v, want the first two", ids)
+	}
+	if thread.NextCursor == "" || thread.PrevCursor != "" {
+		t.Fatalf("first page cursors = %q, %q, want only a next cursor", thread.NextCursor, thread.PrevCursor)
+	}
+
+	thread = get(path + "?limit=2&cursor=" + url.QueryEscape(thread.NextCursor))
+	if ids := chirpIDs(thread.Replies); !slices.Equal(ids, []uuid.UUID{replies[2].ID}) {
+		t.Errorf("second page of replies = %v, want the last one", ids)
+	}
+	if thread.NextCursor != "" || thread.PrevCursor == "" {
+		t.Fatalf("second page cursors = %q, %q, want only a previous cursor", thread.NextCursor, thread.PrevCursor)
+	}
+
+	thread = get(path + "?limit=2&cursor=" + url.QueryEscape(thread.PrevCursor))
+	if ids := chirpIDs(thread.Replies); !slices.Equal(ids, []uuid.UUID{replies[0].ID, replies[1].ID}) {
+		t.Errorf("going back gave replies %v, want the first two", ids)
+	}
+}
+
+func TestGetThreadVisibility(t *testing.T) {
+	author := database.User{ID: uuid.New(), Email: "author@example.com", Role: "user"}
+	other := database.User{ID: uuid.New(), Email: "other@example.com", Role: "user"}
+
+	published := testChirp(author.ID, 0)
+	held := testChirp(author.ID, 1)
+	held.Status = chirpStatusHeld
+	hidden := testChirp(author.ID, 2)
+	hidden.Status = chirpStatusHidden
+	deleted := testChirp(author.ID, 3)
+	deleted.DeletedAt = sql.NullTime{Time: time.Now(), Valid: true}
+
+	tests := []struct {
+		name     string
+		chirp    string
+		viewer   *database.User
+		wantCode int
+	}{
+		{"published", published.ID.String(), nil, http.StatusOK},
+		{"held to others", held.ID.String(), &other, http.StatusNotFound},
+		{"held to anonymous viewers", held.ID.String(), nil, http.StatusNotFound},
+		{"held to its author", held.ID.String(), &author, http.StatusOK},
+		{"hidden to others", hidden.ID.String(), &other, http.StatusNotFound},
+		{"deleted", deleted.ID.String(), nil, http.StatusOK},
+		{"unknown", uuid.NewString(), nil, http.StatusNotFound},
+		{"invalid id", "chirp", nil, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t)
+			cfg, server := newTestServer(t, db, ThreadRoutes)
+			tokens := signIn(t, cfg, db, author, other)
+			token := ""
+			if tt.viewer != nil {
+				token = tokens[0]
+				if tt.viewer.ID == other.ID {
+					token = tokens[1]
+				}
+			}
+
+			chirpsByID(db, published, held, hidden, deleted)
+			noChirpDetails(db)
+			db.on("GetChirpAncestors", returns(rows()))
+			db.on("GetChirpDescendantsAfterCursor", func(args []any) fakeResult {
+				viewer := args[0].(uuid.NullUUID)
+				if viewer.Valid != (tt.viewer != nil) || (tt.viewer != nil && viewer.UUID != tt.viewer.ID) {
+					t.Errorf("replies asked for as viewer %v", viewer)
+				}
+				return rows()
+			})
+
+			w := serve(server, "GET", "/api/chirps/"+tt.chirp+"/thread", token, nil)
+			if w.Code != tt.wantCode {
+				t.Errorf("GET thread = %d %s, want %d", w.Code, w.Body, tt.wantCode)
+			}
+		})
+	}
+}
+
+func chirpIDs(chirps []Chirp) []uuid.UUID {
+	ids := []uuid.UUID{}
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+	return ids
+}
@@ -4,7 +4,12 @@ import (
 	"chirpy/internal/auth"
 	"chirpy/internal/database"
 	"chirpy/util"
+	"context"
+	"database/sql"
+	"errors"
+	"log"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,121 +21,311 @@ func TokenRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
 	s.Handle("POST /api/revoke", http.HandlerFunc(apiConfig.revoke))
 }
 
+// Hashed with the current parameters on first use, after main has set them
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, err := auth.HashPassword("not a real password")
+	if err != nil {
+		log.Fatalf("hashing dummy password: %v", err)
+	}
+	return hash
+})
+
 func (cfg *ApiConfig) login(w http.ResponseWriter, r *http.Request) {
 	type loginRequest struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		// Optional name for the session, e.g. "Work laptop"
+		Label string `json:"label"`
 	}
 	params, err := util.DecodeJSON[loginRequest](r)
 	if util.ErrorNotNil(err, w) {
 		return
 	}
 
+	if !cfg.loginAllowed(w, r, params.Email) {
+		return
+	}
+
+	// Unknown emails are checked against a dummy hash, so they take as
+	// long to reject as wrong passwords and do not reveal who has an account
 	searchedUser, err := cfg.DbQueries.GetUserByEmail(r.Context(), params.Email)
+	hash := searchedUser.HashedPassword
 	if err != nil {
+		hash = dummyPasswordHash()
+	}
+	if auth.CheckPasswordHash(hash, params.Password) != nil || err != nil {
 		util.RespondWithError(w, 401, struct {
 			Error string `json:"error"`
 		}{Error: "Incorrect email or password"})
 		return
 	}
 
-	if auth.CheckPasswordHash(searchedUser.HashedPassword, params.Password) != nil {
+	err = cfg.LoginThrottle.Release(r.Context(), r, params.Email)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
 
-		util.RespondWithError(w, 401, struct {
-			Error string `json:"error"`
-		}{Error: "Incorrect email or password"})
+	// The password is known to be right, so upgrade legacy bcrypt and
+	// under-strength hashes while we have it
+	if auth.NeedsRehash(searchedUser.HashedPassword) {
+		hashedPassword, err := auth.HashPassword(params.Password)
+		if err == nil {
+			err = cfg.DbQueries.UpdatePassword(r.Context(), database.UpdatePasswordParams{
+				HashedPassword: hashedPassword,
+				ID:             searchedUser.ID,
+			})
+		}
+		if err != nil {
+			log.Printf("rehashing password of user %s: %v", searchedUser.ID, err)
+		}
+	}
+
+	if searchedUser.SuspendedAt.Valid {
+		util.RespondWithError(w, http.StatusForbidden, util.ResponseError{Error: "account suspended"})
 		return
 	}
 
-	// All okay, generate the token
-	token, err := auth.MakeJWT(searchedUser.ID, cfg.JwtSecret, time.Duration(1)*time.Hour)
+	// With 2FA on the password only earns a challenge, the session is
+	// started by POST /api/login/2fa
+	if searchedUser.TotpEnabledAt.Valid {
+		challenge, err := auth.MakeChallengeJWT(searchedUser.ID, cfg.Keys, challengeLifetime)
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+		util.RespondWithJSON(w, 200, struct {
+			TwoFactorRequired bool   `json:"two_factor_required"`
+			ChallengeToken    string `json:"challenge_token"`
+		}{TwoFactorRequired: true, ChallengeToken: challenge})
+		return
+	}
+
+	cfg.startSession(w, r, searchedUser, params.Label)
+}
+
+// Responds to a successful login with an access token and the first
+// refresh token of a new session
+func (cfg *ApiConfig) startSession(w http.ResponseWriter, r *http.Request, searchedUser database.User, label string) {
+	// A completed login clears the account's failed attempts
+	err := cfg.LoginThrottle.Succeed(r.Context(), searchedUser.Email)
 	if util.ErrorNotNil(err, w) {
 		return
 	}
 
-	refreshToken, err := auth.MakeRefreshToken()
+	// All okay, generate the token. Each login starts a new session, which
+	// is a new family of refresh tokens.
+	sessionID := uuid.New()
+	token, err := auth.MakeJWT(searchedUser.ID, auth.Role(searchedUser.Role), sessionID, cfg.Keys, time.Duration(1)*time.Hour)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	refreshToken, err := issueRefreshToken(r.Context(), cfg.DbQueries, searchedUser.ID, sessionID, requestDevice(r, label), clientGrant{})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	isChirpyRed, err := cfg.isChirpyRed(r.Context(), searchedUser.ID)
 	if util.ErrorNotNil(err, w) {
 		return
 	}
 
 	type User struct {
-		ID           uuid.UUID `json:"id"`
-		CreatedAt    time.Time `json:"created_at"`
-		UpdatedAt    time.Time `json:"updated_at"`
-		Email        string    `json:"email"`
-		Token        string    `json:"token"`
-		RefreshToken string    `json:"refresh_token"`
-		IsChirpyRed  bool      `json:"is_chirpy_red"`
+		ID            uuid.UUID `json:"id"`
+		CreatedAt     time.Time `json:"created_at"`
+		UpdatedAt     time.Time `json:"updated_at"`
+		Email         string    `json:"email"`
+		Handle        *string   `json:"handle"`
+		Token         string    `json:"token"`
+		RefreshToken  string    `json:"refresh_token"`
+		IsChirpyRed   bool      `json:"is_chirpy_red"`
+		Role          string    `json:"role"`
+		EmailVerified bool      `json:"email_verified"`
 	}
 
 	userLoginResponse := User{
-		ID:           searchedUser.ID,
-		CreatedAt:    searchedUser.CreatedAt,
-		UpdatedAt:    searchedUser.UpdatedAt,
-		Email:        searchedUser.Email,
-		Token:        token,
-		RefreshToken: refreshToken,
-		IsChirpyRed:  searchedUser.IsChirpyRed,
+		ID:            searchedUser.ID,
+		CreatedAt:     searchedUser.CreatedAt,
+		UpdatedAt:     searchedUser.UpdatedAt,
+		Email:         searchedUser.Email,
+		Handle:        nullableString(searchedUser.Handle),
+		Token:         token,
+		RefreshToken:  refreshToken,
+		IsChirpyRed:   isChirpyRed,
+		Role:          searchedUser.Role,
+		EmailVerified: searchedUser.EmailVerifiedAt.Valid,
 	}
 
-	// Log into refresh token into DB
-	refreshTokenParams := database.CreateRefreshTokenParams{
-		Token:     refreshToken,
-		UserID:    searchedUser.ID,
-		ExpiresAt: time.Now().Add(60 * 24 * time.Hour),
+	util.RespondWithJSON(w, 200, userLoginResponse)
+
+}
+
+const refreshTokenLifetime = 60 * 24 * time.Hour
+
+// The client and scopes a refresh token was granted to. Zero for the
+// users' own sessions.
+type clientGrant struct {
+	ClientID uuid.NullUUID
+	Scopes   []string
+}
+
+// Creates and stores a refresh token belonging to the given family
+func issueRefreshToken(ctx context.Context, queries *database.Queries, userID, familyID uuid.UUID, device sessionDevice, client clientGrant) (string, error) {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
 	}
-	cfg.DbQueries.CreateRefreshToken(r.Context(), refreshTokenParams)
 
-	util.RespondWithJSON(w, 200, userLoginResponse)
+	_, err = queries.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:     token,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(refreshTokenLifetime),
+		FamilyID:  familyID,
+		UserAgent: device.UserAgent,
+		Ip:        device.IP,
+		Label:     device.Label,
+		ClientID:  client.ClientID,
+		Scopes:    client.Scopes,
+	})
+	return token, err
+}
+
+var (
+	errRefreshTokenInvalid = errors.New("Invalid token")
+	errRefreshTokenExpired = errors.New("Expired token")
+	errRefreshTokenReused  = errors.New("Refresh token reuse detected, all sessions from this login were revoked")
+)
+
+// Looks up a presented refresh token that can still be used
+func (cfg *ApiConfig) usableRefreshToken(ctx context.Context, token string) (database.RefreshToken, error) {
+	refreshToken, err := cfg.DbQueries.GetRefreshToken(ctx, token)
+	if err == sql.ErrNoRows {
+		return refreshToken, errRefreshTokenInvalid
+	}
+	if err != nil {
+		return refreshToken, err
+	}
+
+	if time.Now().Compare(refreshToken.ExpiresAt) > 0 || refreshToken.RevokedAt.Valid {
+		return refreshToken, errRefreshTokenExpired
+	}
+	return refreshToken, nil
+}
+
+// Replaces a refresh token with the next one of its family, keeping the
+// client grant. Presenting a token that has already been rotated means it
+// was copied, so the whole family is revoked and both holders have to log
+// in again.
+func (cfg *ApiConfig) rotateRefreshToken(r *http.Request, refreshToken database.RefreshToken) (string, error) {
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
+
+	// Rotating only succeeds once, a concurrent replay loses the race here
+	_, err = queries.RotateRefreshToken(r.Context(), refreshToken.Token)
+	if err == sql.ErrNoRows {
+		err = cfg.DbQueries.RevokeRefreshTokenFamily(r.Context(), refreshToken.FamilyID)
+		if err != nil {
+			return "", err
+		}
+		return "", errRefreshTokenReused
+	}
+	if err != nil {
+		return "", err
+	}
+
+	newRefreshToken, err := issueRefreshToken(r.Context(), queries, refreshToken.UserID, refreshToken.FamilyID,
+		requestDevice(r, refreshToken.Label), clientGrant{ClientID: refreshToken.ClientID, Scopes: refreshToken.Scopes})
+	if err != nil {
+		return "", err
+	}
 
+	return newRefreshToken, tx.Commit()
 }
 
+// Swaps a refresh token for a new access token and a new refresh token
 func (cfg *ApiConfig) refresh(w http.ResponseWriter, r *http.Request) {
 
 	authToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		util.RespondWithError(w, 401, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	refreshToken, err := cfg.usableRefreshToken(r.Context(), authToken)
+	// Tokens granted to OAuth clients are refreshed at /oauth/token
+	if err == nil && refreshToken.ClientID.Valid {
+		err = errRefreshTokenInvalid
+	}
+	if err == errRefreshTokenInvalid || err == errRefreshTokenExpired {
+		util.RespondWithError(w, 401, util.ResponseError{Error: err.Error()})
+		return
+	}
 	if util.ErrorNotNil(err, w) {
 		return
 	}
 
-	refreshToken, err := cfg.DbQueries.GetRefreshToken(r.Context(), authToken)
-	if err != nil {
-		util.RespondWithError(w, 401, struct {
-			Error string `json:"error"`
-		}{Error: "Invalid token"})
+	user, ok := cfg.activeUser(w, r, refreshToken.UserID)
+	if !ok {
 		return
 	}
 
-	if time.Now().Compare(refreshToken.ExpiresAt) > 0 || refreshToken.RevokedAt.Valid {
-		util.RespondWithError(w, 401, struct {
-			Error string `json:"error"`
-		}{Error: "Expired token"})
+	newRefreshToken, err := cfg.rotateRefreshToken(r, refreshToken)
+	if err == errRefreshTokenReused {
+		util.RespondWithError(w, 401, util.ResponseError{Error: err.Error()})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
 		return
 	}
 
-	// Good to create the access token!
-	accessToken, err := auth.MakeJWT(refreshToken.UserID, cfg.JwtSecret, time.Hour)
+	// Good to create the access token! The role is read afresh so role
+	// changes take effect on the next refresh
+	accessToken, err := auth.MakeJWT(user.ID, auth.Role(user.Role), refreshToken.FamilyID, cfg.Keys, time.Hour)
 	if util.ErrorNotNil(err, w) {
 		return
 	}
 
 	util.RespondWithJSON(w, 200, struct {
-		Token string `json:"token"`
-	}{Token: accessToken})
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}{Token: accessToken, RefreshToken: newRefreshToken})
 
 }
 
+// Revokes the refresh token's family, ending that login. With ?all=true
+// every session belonging to the token's user is revoked instead.
 func (cfg *ApiConfig) revoke(w http.ResponseWriter, r *http.Request) {
 
 	authToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		util.RespondWithError(w, 401, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	// Only a token that could still be refreshed proves the caller holds
+	// the session, and with ?all=true every other session of the user
+	refreshToken, err := cfg.usableRefreshToken(r.Context(), authToken)
+	if err == nil && refreshToken.RotatedAt.Valid {
+		err = errRefreshTokenExpired
+	}
+	if err == errRefreshTokenInvalid || err == errRefreshTokenExpired {
+		util.RespondWithError(w, 401, util.ResponseError{Error: err.Error()})
+		return
+	}
 	if util.ErrorNotNil(err, w) {
 		return
 	}
 
-	err = cfg.DbQueries.RevokeRefreshToken(r.Context(), authToken)
-	if err != nil {
-		util.RespondWithError(w, 404, struct {
-			Error string `json:"error"`
-		}{Error: "Auth token not found"})
+	if r.URL.Query().Get("all") == "true" {
+		err = cfg.DbQueries.RevokeUserRefreshTokens(r.Context(), refreshToken.UserID)
+	} else {
+		err = cfg.DbQueries.RevokeRefreshTokenFamily(r.Context(), refreshToken.FamilyID)
+	}
+	if util.ErrorNotNil(err, w) {
+		return
 	}
 
 	w.WriteHeader(204)
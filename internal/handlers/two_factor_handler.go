@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/util"
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func TwoFactorRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
+	s.Handle("POST /api/users/2fa", http.HandlerFunc(apiConfig.enrollTwoFactor))
+	s.Handle("POST /api/users/2fa/confirm", http.HandlerFunc(apiConfig.confirmTwoFactor))
+	s.Handle("DELETE /api/users/2fa", http.HandlerFunc(apiConfig.disableTwoFactor))
+	s.Handle("POST /api/login/2fa", http.HandlerFunc(apiConfig.loginTwoFactor))
+}
+
+const (
+	challengeLifetime = 5 * time.Minute
+	recoveryCodeCount = 10
+	totpIssuer        = "Chirpy"
+)
+
+type secondFactorRequest struct {
+	Code string `json:"code"`
+}
+
+// Starts enrollment with a new secret and recovery codes. 2FA is not
+// enforced until the secret is confirmed with a code from the app.
+func (cfg *ApiConfig) enrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserById(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	if user.TotpEnabledAt.Valid {
+		util.RespondWithError(w, http.StatusConflict, util.ResponseError{Error: "two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
+
+	err = queries.SetTOTPSecret(r.Context(), database.SetTOTPSecretParams{
+		TotpSecret: sql.NullString{String: secret, Valid: true},
+		ID:         userID,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = queries.DeleteRecoveryCodes(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	for _, code := range codes {
+		hash, err := auth.HashPassword(code)
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+		err = queries.CreateRecoveryCode(r.Context(), database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hash,
+		})
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+	}
+
+	err = tx.Commit()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusCreated, struct {
+		OtpauthURI    string   `json:"otpauth_uri"`
+		Secret        string   `json:"secret"`
+		RecoveryCodes []string `json:"recovery_codes"`
+	}{
+		OtpauthURI:    auth.TOTPURI(totpIssuer, user.Email, secret),
+		Secret:        secret,
+		RecoveryCodes: codes,
+	})
+}
+
+func (cfg *ApiConfig) confirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	params, err := util.DecodeJSON[secondFactorRequest](r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserById(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	if !user.TotpSecret.Valid || user.TotpEnabledAt.Valid {
+		util.RespondWithError(w, http.StatusConflict, util.ResponseError{Error: "no two-factor enrollment to confirm"})
+		return
+	}
+
+	// Recovery codes are no proof the authenticator was set up correctly
+	ok, err := cfg.checkTOTP(r.Context(), user, params.Code)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if !ok {
+		util.RespondWithError(w, http.StatusUnauthorized, util.ResponseError{Error: "invalid code"})
+		return
+	}
+
+	err = cfg.DbQueries.EnableTOTP(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (cfg *ApiConfig) disableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, err := cfg.authenticate(r)
+	if err != nil {
+		respondAuthError(w, err)
+		return
+	}
+
+	params, err := util.DecodeJSON[secondFactorRequest](r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	user, err := cfg.DbQueries.GetUserById(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	if !user.TotpSecret.Valid {
+		util.RespondWithError(w, http.StatusConflict, util.ResponseError{Error: "two-factor authentication is not enabled"})
+		return
+	}
+
+	// A stolen access token alone must not be enough to turn 2FA off
+	ok, err := cfg.checkSecondFactor(r.Context(), user, params.Code)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if !ok {
+		util.RespondWithError(w, http.StatusUnauthorized, util.ResponseError{Error: "invalid code"})
+		return
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
+
+	err = queries.DisableTOTP(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = queries.DeleteRecoveryCodes(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = tx.Commit()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Second step of a login for accounts with 2FA, exchanging the challenge
+// from POST /api/login and a code for the usual login response
+func (cfg *ApiConfig) loginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	type loginTwoFactorRequest struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		Label          string `json:"label"`
+	}
+	params, err := util.DecodeJSON[loginTwoFactorRequest](r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	userID, err := auth.ValidateChallengeJWT(params.ChallengeToken, cfg.Keys)
+	if err != nil {
+		util.RespondWithError(w, http.StatusUnauthorized, util.ResponseError{Error: "invalid or expired challenge"})
+		return
+	}
+
+	user, ok := cfg.activeUser(w, r, userID)
+	if !ok {
+		return
+	}
+
+	// Wrong codes count against the account like wrong passwords
+	if !cfg.loginAllowed(w, r, user.Email) {
+		return
+	}
+
+	ok, err = cfg.checkSecondFactor(r.Context(), user, params.Code)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	if !ok {
+		util.RespondWithError(w, http.StatusUnauthorized, util.ResponseError{Error: "invalid code"})
+		return
+	}
+
+	err = cfg.LoginThrottle.Release(r.Context(), r, user.Email)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	cfg.startSession(w, r, user, params.Label)
+}
+
+// Accepts a code from the authenticator app at most once
+func (cfg *ApiConfig) checkTOTP(ctx context.Context, user database.User, code string) (bool, error) {
+	if !user.TotpSecret.Valid {
+		return false, nil
+	}
+
+	step, ok := auth.ValidateTOTP(user.TotpSecret.String, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	used, err := cfg.DbQueries.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		TotpLastStep: step,
+		ID:           user.ID,
+	})
+	return used == 1, err
+}
+
+// Accepts either a code from the authenticator app or an unused recovery
+// code, which is then spent
+func (cfg *ApiConfig) checkSecondFactor(ctx context.Context, user database.User, code string) (bool, error) {
+	ok, err := cfg.checkTOTP(ctx, user, code)
+	if ok || err != nil {
+		return ok, err
+	}
+
+	recoveryCodes, err := cfg.DbQueries.GetUnusedRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	code = strings.ToLower(strings.TrimSpace(code))
+	for _, recoveryCode := range recoveryCodes {
+		if auth.CheckPasswordHash(recoveryCode.CodeHash, code) != nil {
+			continue
+		}
+		used, err := cfg.DbQueries.UseRecoveryCode(ctx, recoveryCode.ID)
+		return used == 1, err
+	}
+	return false, nil
+}
@@ -3,11 +3,17 @@ package handlers
 import (
 	"chirpy/internal/auth"
 	"chirpy/internal/database"
+	"chirpy/internal/entities"
+	"chirpy/internal/events"
 	"chirpy/util"
+	"database/sql"
+	"errors"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 func UserRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
@@ -19,11 +25,24 @@ func (cfg *ApiConfig) addUser(w http.ResponseWriter, r *http.Request) {
 	type createUserRequest struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 	params, err := util.DecodeJSON[createUserRequest](r)
 	if util.ErrorNotNil(err, w) {
 		return
 	}
+
+	handle, err := parseHandle(params.Handle)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+	err = auth.CheckPasswordStrength(params.Password, params.Email, params.Handle)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
 	hashed_password, err := auth.HashPassword(params.Password)
 	if util.ErrorNotNil(err, w) {
 		return
@@ -32,27 +51,60 @@ func (cfg *ApiConfig) addUser(w http.ResponseWriter, r *http.Request) {
 	createUserParams := database.CreateUserParams{
 		Email:          params.Email,
 		HashedPassword: hashed_password,
+		Handle:         handle,
 	}
 
-	user, err := cfg.DbQueries.CreateUser(r.Context(), createUserParams)
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
+
+	user, err := queries.CreateUser(r.Context(), createUserParams)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = events.Publish(r.Context(), queries, events.UserCreated{
+		ID:        user.ID,
+		CreatedAt: user.CreatedAt,
+		Handle:    nullableString(user.Handle),
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = tx.Commit()
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = cfg.sendVerificationEmail(user)
 	if util.ErrorNotNil(err, w) {
 		return
 	}
 
 	type User struct {
-		ID          uuid.UUID `json:"id"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-		Email       string    `json:"email"`
-		IsChirpyRed bool      `json:"is_chirpy_red"`
+		ID            uuid.UUID `json:"id"`
+		CreatedAt     time.Time `json:"created_at"`
+		UpdatedAt     time.Time `json:"updated_at"`
+		Email         string    `json:"email"`
+		Handle        *string   `json:"handle"`
+		IsChirpyRed   bool      `json:"is_chirpy_red"`
+		Role          string    `json:"role"`
+		EmailVerified bool      `json:"email_verified"`
 	}
 
 	userCreatedResponse := User{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       params.Email,
-		IsChirpyRed: user.IsChirpyRed,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         params.Email,
+		Handle:        nullableString(user.Handle),
+		IsChirpyRed:   false,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
 	}
 
 	util.RespondWithJSON(w, 201, userCreatedResponse)
@@ -70,58 +122,192 @@ func (cfg *ApiConfig) updateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := auth.ValidateJWT(accessToken, cfg.JwtSecret)
-	if err != nil {
-		util.RespondWithError(w, http.StatusUnauthorized, err.Error())
+	type updateParams struct {
+		Password string  `json:"password"`
+		Email    string  `json:"email"`
+		Handle   *string `json:"handle"`
+		// Required to change the email or password
+		CurrentPassword string `json:"current_password"`
+	}
+
+	params, err := util.DecodeJSON[updateParams](r)
+	if util.ErrorNotNil(err, w) {
 		return
 	}
 
-	type updateParams struct {
-		Password string `json:"password"`
-		Email    string `json:"email"`
+	// profile:write only reaches the handle. The email and password take
+	// the user's own JWT and their current password, so a leaked personal
+	// access token or a third-party client cannot take over the account.
+	changesCredentials := params.Email != "" || params.Password != ""
+	var userID uuid.UUID
+	if changesCredentials {
+		userID, err = cfg.authenticate(r)
+	} else {
+		userID, err = cfg.authenticateScope(r, auth.ScopeProfileWrite)
+	}
+	if err != nil {
+		respondAuthError(w, err)
+		return
 	}
 
-	params, err := util.DecodeJSON[updateParams](r)
+	previous, err := cfg.DbQueries.GetUserById(r.Context(), userID)
 	if util.ErrorNotNil(err, w) {
 		return
 	}
 
-	hashedPass, err := auth.HashPassword(params.Password)
+	if changesCredentials && auth.CheckPasswordHash(previous.HashedPassword, params.CurrentPassword) != nil {
+		util.RespondWithError(w, http.StatusUnauthorized, util.ResponseError{Error: "Incorrect current password"})
+		return
+	}
+
+	// Everything is checked before anything is written, so a rejected
+	// request changes nothing. The handle is only changed when present, an
+	// empty string clears it.
+	handle := previous.Handle
+	if params.Handle != nil {
+		handle, err = parseHandle(*params.Handle)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+			return
+		}
+	}
+
+	// A missing email or password keeps the current one
+	email := params.Email
+	if email == "" {
+		email = previous.Email
+	}
+	hashedPass := previous.HashedPassword
+	if params.Password != "" {
+		err = auth.CheckPasswordStrength(params.Password, email, previous.Email, handle.String)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+			return
+		}
+
+		hashedPass, err = auth.HashPassword(params.Password)
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+	}
+
+	tx, err := cfg.DB.BeginTx(r.Context(), nil)
 	if util.ErrorNotNil(err, w) {
 		return
 	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
 
-	dbParams := database.UpdateEmailandPasswordParams{
-		HashedPassword: hashedPass,
-		Email:          params.Email,
-		ID:             userID,
+	if params.Handle != nil {
+		err = queries.UpdateHandle(r.Context(), database.UpdateHandleParams{
+			Handle: handle,
+			ID:     userID,
+		})
+		if isUniqueViolation(err) {
+			util.RespondWithError(w, http.StatusConflict, util.ResponseError{Error: "handle is already taken"})
+			return
+		}
+		if util.ErrorNotNil(err, w) {
+			return
+		}
 	}
-	err = cfg.DbQueries.UpdateEmailandPassword(r.Context(), dbParams)
+
+	if changesCredentials {
+		err = queries.UpdateEmailandPassword(r.Context(), database.UpdateEmailandPasswordParams{
+			HashedPassword: hashedPass,
+			Email:          email,
+			ID:             userID,
+		})
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+	}
+
+	user, err := queries.GetUserById(r.Context(), userID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = tx.Commit()
 	if util.ErrorNotNil(err, w) {
 		return
 	}
 
-	user, err := cfg.DbQueries.GetUserByEmail(r.Context(), dbParams.Email)
+	// Changing the email clears its verification, prove the new one
+	if user.Email != previous.Email {
+		err = cfg.sendVerificationEmail(user)
+		if util.ErrorNotNil(err, w) {
+			return
+		}
+	}
+
+	isChirpyRed, err := cfg.isChirpyRed(r.Context(), user.ID)
 	if util.ErrorNotNil(err, w) {
 		return
 	}
 
 	type User struct {
-		ID          uuid.UUID `json:"id"`
-		CreatedAt   time.Time `json:"created_at"`
-		UpdatedAt   time.Time `json:"updated_at"`
-		Email       string    `json:"email"`
-		IsChirpyRed bool      `json:"is_chirpy_red"`
+		ID            uuid.UUID `json:"id"`
+		CreatedAt     time.Time `json:"created_at"`
+		UpdatedAt     time.Time `json:"updated_at"`
+		Email         string    `json:"email"`
+		Handle        *string   `json:"handle"`
+		IsChirpyRed   bool      `json:"is_chirpy_red"`
+		Role          string    `json:"role"`
+		EmailVerified bool      `json:"email_verified"`
 	}
 
 	userResponse := User{
-		ID:          user.ID,
-		CreatedAt:   user.CreatedAt,
-		UpdatedAt:   user.UpdatedAt,
-		Email:       user.Email,
-		IsChirpyRed: user.IsChirpyRed,
+		ID:            user.ID,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+		Email:         user.Email,
+		Handle:        nullableString(user.Handle),
+		IsChirpyRed:   isChirpyRed,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
 	}
 
 	util.RespondWithJSON(w, 200, userResponse)
 
 }
+
+// Folds a requested handle into its stored form; empty means no handle
+func parseHandle(handle string) (sql.NullString, error) {
+	if handle == "" {
+		return sql.NullString{}, nil
+	}
+
+	folded := entities.Fold(strings.TrimPrefix(handle, "@"))
+	if !entities.ValidHandle(folded) {
+		return sql.NullString{}, errors.New("handle must be 1-30 letters, digits or underscores")
+	}
+	return sql.NullString{String: folded, Valid: true}, nil
+}
+
+// Reports whether err is Postgres refusing a duplicate in a unique column
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func nullableString(s sql.NullString) *string {
+	if !s.Valid {
+		return nil
+	}
+	return &s.String
+}
+
+func nullableTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func nullableUUID(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
@@ -2,64 +2,251 @@ package handlers
 
 import (
 	"chirpy/internal/auth"
+	"chirpy/internal/database"
 	"chirpy/util"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 func WebhookRoutes(s *http.ServeMux, apiConfig *ApiConfig) {
 	s.Handle("POST /api/polka/webhooks", http.HandlerFunc(apiConfig.handleEvent))
-
+	s.Handle("GET /admin/webhooks/events", apiConfig.requireRole(auth.RoleAdmin, apiConfig.getWebhookEvents))
+	s.Handle("POST /admin/webhooks/events/{eventID}/replay", apiConfig.requireRole(auth.RoleAdmin, apiConfig.replayWebhookEvent))
 }
 
+const (
+	polkaSource = "polka"
+	// Header carrying the signature made with SignWebhook
+	polkaSignatureHeader = "Polka-Signature"
+	maxWebhookBody       = 1 << 20
+)
+
+// Events are received, then processed or failed
+const webhookProcessed = "processed"
+
+var (
+	errWebhookProcessed = errors.New("event has already been processed")
+	// Wraps the reason an event could not be applied, which is recorded
+	// on the event
+	errWebhookFailed = errors.New("processing event failed")
+)
+
+// Receives Polka's events. Deliveries are signed over the raw body and
+// logged by event ID, so a retried delivery is only processed once.
 func (cfg *ApiConfig) handleEvent(w http.ResponseWriter, r *http.Request) {
-	type webHookEvent struct {
-		Data struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
-		Event string `json:"event"`
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
 	}
 
-	apiKey, err := auth.GetAPIKey(r.Header)
+	err = auth.VerifyWebhook(cfg.PolkaWebhookSecret, r.Header.Get(polkaSignatureHeader), body, time.Now(), auth.WebhookTolerance)
 	if err != nil {
-		util.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		util.RespondWithError(w, http.StatusUnauthorized, util.ResponseError{Error: err.Error()})
 		return
 	}
 
-	if apiKey != cfg.PolkaKey {
-		util.RespondWithError(w, http.StatusUnauthorized, util.ResponseMessage{
-			Message: "invalid key",
-		})
+	var params polkaEvent
+	err = json.Unmarshal(body, &params)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+	if params.ID == "" || params.Event == "" {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "event id and type are required"})
 		return
 	}
 
-	params, err := util.DecodeJSON[webHookEvent](r)
-	if err != nil {
-		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+	event, err := cfg.DbQueries.RecordWebhookEvent(r.Context(), database.RecordWebhookEventParams{
+		Source:    polkaSource,
+		EventID:   params.ID,
+		EventType: params.Event,
+		Payload:   body,
+	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	err = cfg.processWebhookEvent(r.Context(), event.ID)
+	if err == errWebhookProcessed {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if errors.Is(err, errUnknownUser) {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: errUnknownUser.Error()})
+		return
+	}
+	if util.ErrorNotNil(err, w) {
 		return
 	}
 
-	if params.Event == "user.upgraded" {
+	w.WriteHeader(http.StatusNoContent)
+}
 
-		user_uuid, err := uuid.Parse(params.Data.UserID)
-		if err != nil {
-			util.RespondWithError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
+// Applies a logged event unless it has been processed already, recording
+// the outcome. The row stays locked while the event is applied, so
+// concurrent deliveries of the same event wait for each other.
+func (cfg *ApiConfig) processWebhookEvent(ctx context.Context, eventID uuid.UUID) error {
+	tx, err := cfg.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := cfg.DbQueries.WithTx(tx)
+
+	event, err := queries.LockWebhookEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if event.Status == webhookProcessed {
+		return errWebhookProcessed
+	}
 
-		err = cfg.DbQueries.GrantChirpyRed(r.Context(), user_uuid)
-		if err != nil {
-			util.RespondWithError(w, http.StatusNotFound, err.Error())
-			return
+	var params polkaEvent
+	err = json.Unmarshal(event.Payload, &params)
+	if err == nil {
+		err = applyPolkaEvent(ctx, queries, params)
+	}
+	if err != nil {
+		// Undo what the event changed, but keep the failure on record.
+		// The lock goes with the rollback, so the query leaves events a
+		// concurrent delivery has processed in the meantime alone.
+		tx.Rollback()
+		failErr := cfg.DbQueries.MarkWebhookEventFailed(ctx, database.MarkWebhookEventFailedParams{
+			ID:        eventID,
+			LastError: sql.NullString{String: err.Error(), Valid: true},
+		})
+		if failErr != nil {
+			return failErr
 		}
+		return fmt.Errorf("%w: %w", errWebhookFailed, err)
+	}
 
-		util.RespondWithJSON(w, http.StatusNoContent, util.ResponseMessage{
-			Message: "user upgraded",
+	err = queries.MarkWebhookEventProcessed(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type WebhookEvent struct {
+	ID          uuid.UUID       `json:"id"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Source      string          `json:"source"`
+	EventID     string          `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	LastError   *string         `json:"last_error"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+}
+
+func webhookEventResponse(event database.WebhookEvent) WebhookEvent {
+	return WebhookEvent{
+		ID:          event.ID,
+		CreatedAt:   event.CreatedAt,
+		UpdatedAt:   event.UpdatedAt,
+		Source:      event.Source,
+		EventID:     event.EventID,
+		EventType:   event.EventType,
+		Payload:     event.Payload,
+		Status:      event.Status,
+		Attempts:    event.Attempts,
+		LastError:   nullableString(event.LastError),
+		ProcessedAt: nullableTime(event.ProcessedAt),
+	}
+}
+
+// Lists received events, filtered with ?status=failed and the like
+func (cfg *ApiConfig) getWebhookEvents(w http.ResponseWriter, r *http.Request) {
+	page, err := parsePageRequest(r)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: err.Error()})
+		return
+	}
+
+	status := sql.NullString{}
+	if s := r.URL.Query().Get("status"); s != "" {
+		status = sql.NullString{String: s, Valid: true}
+	}
+
+	after := func(c *cursor, limit int32) ([]database.WebhookEvent, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetWebhookEventsAfterCursor(r.Context(), database.GetWebhookEventsAfterCursorParams{
+			Status:          status,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
+		})
+	}
+	before := func(c *cursor, limit int32) ([]database.WebhookEvent, error) {
+		createdAt, id := c.params()
+		return cfg.DbQueries.GetWebhookEventsBeforeCursor(r.Context(), database.GetWebhookEventsBeforeCursorParams{
+			Status:          status,
+			CursorCreatedAt: createdAt,
+			CursorID:        id,
+			PageLimit:       limit,
 		})
 	}
 
-	util.RespondWithError(w, http.StatusNoContent, util.ResponseMessage{
-		Message: "unknown event",
+	result, err := fetchPage(page, after, before, func(event database.WebhookEvent) cursor {
+		return cursor{CreatedAt: event.CreatedAt, ID: event.ID}
 	})
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	events := []WebhookEvent{}
+	for _, event := range result.Items {
+		events = append(events, webhookEventResponse(event))
+	}
+
+	setLinkHeader(w, r, page, result)
+	util.RespondWithJSON(w, 200, struct {
+		Events     []WebhookEvent `json:"events"`
+		NextCursor string         `json:"next_cursor,omitempty"`
+		PrevCursor string         `json:"prev_cursor,omitempty"`
+	}{Events: events, NextCursor: result.NextCursor, PrevCursor: result.PrevCursor})
+}
+
+// Processes a logged event again, e.g. once the cause of its failure has
+// been fixed. Processed events are not replayed.
+func (cfg *ApiConfig) replayWebhookEvent(w http.ResponseWriter, r *http.Request) {
+	eventID, err := uuid.Parse(r.PathValue("eventID"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, util.ResponseError{Error: "invalid event id"})
+		return
+	}
+
+	err = cfg.processWebhookEvent(r.Context(), eventID)
+	if err == sql.ErrNoRows {
+		util.RespondWithError(w, http.StatusNotFound, util.ResponseError{Error: "Event not found"})
+		return
+	}
+	if err == errWebhookProcessed {
+		util.RespondWithError(w, http.StatusConflict, util.ResponseError{Error: err.Error()})
+		return
+	}
+	// A failed replay is recorded on the event, which is returned either way
+	if err != nil && !errors.Is(err, errWebhookFailed) {
+		util.ErrorNotNil(err, w)
+		return
+	}
+
+	event, err := cfg.DbQueries.GetWebhookEvent(r.Context(), eventID)
+	if util.ErrorNotNil(err, w) {
+		return
+	}
+
+	util.RespondWithJSON(w, 200, webhookEventResponse(event))
 }
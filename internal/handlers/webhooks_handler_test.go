@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHandleEvent(t *testing.T) {
+	user := database.User{ID: uuid.New(), Email: "user@example.com", Role: "user"}
+	upgraded := `{"id": "evt_1", "event": "user.upgraded", "data": {"user_id": "` + user.ID.String() + `", "plan": "red"}}`
+
+	tests := []struct {
+		name string
+		body string
+		// Signs the body with the secret, the right one when empty
+		secret string
+		// Status the event was logged with by an earlier delivery
+		status string
+		// Error starting the subscription
+		startErr  error
+		wantCode  int
+		wantEvent bool
+		// The status the event is left in, if it is processed at all
+		wantStatus string
+	}{
+		{"upgrade", upgraded, "", "", nil, http.StatusNoContent, true, "processed"},
+		{"retried failure", upgraded, "", "failed", nil, http.StatusNoContent, true, "processed"},
+		{"already processed", upgraded, "", "processed", nil, http.StatusNoContent, false, ""},
+		{"wrong signature", upgraded, "other", "", nil, http.StatusUnauthorized, false, ""},
+		{"not json", "user.upgraded", "", "", nil, http.StatusBadRequest, false, ""},
+		{"no event id", `{"event": "user.upgraded"}`, "", "", nil, http.StatusBadRequest, false, ""},
+		{"unknown user", `{"id": "evt_2", "event": "user.upgraded", "data": {"user_id": "` + uuid.NewString() + `"}}`, "", "", nil, http.StatusNotFound, false, "failed"},
+		{"ignored event", `{"id": "evt_3", "event": "user.renamed", "data": {"user_id": "` + user.ID.String() + `"}}`, "", "", nil, http.StatusNoContent, false, "processed"},
+		{"failing database", upgraded, "", "", errors.New("connection reset"), http.StatusInternalServerError, false, "failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newFakeDB(t)
+			cfg, server := newTestServer(t, db, WebhookRoutes)
+			cfg.PolkaWebhookSecret = "whsec"
+			signIn(t, cfg, db, user)
+
+			event := database.WebhookEvent{ID: uuid.New(), Status: "received"}
+			if tt.status != "" {
+				event.Status = tt.status
+			}
+			db.on("RecordWebhookEvent", func(args []any) fakeResult {
+				event.Source = args[0].(string)
+				event.EventID = args[1].(string)
+				event.EventType = args[2].(string)
+				event.Payload = args[3].(json.RawMessage)
+				return rows(event)
+			})
+			db.on("LockWebhookEvent", func(args []any) fakeResult {
+				return rows(event)
+			})
+			db.on("StartSubscription", func(args []any) fakeResult {
+				if tt.startErr != nil {
+					return failed(tt.startErr)
+				}
+				return rows(database.Subscription{
+					ID:                 uuid.New(),
+					UserID:             args[0].(uuid.UUID),
+					Plan:               args[1].(string),
+					Status:             subscriptionActive,
+					CurrentPeriodStart: args[2].(time.Time),
+					CurrentPeriodEnd:   args[3].(time.Time),
+				})
+			})
+			db.on("InsertOutboxEvent", returns(affected(1)))
+			db.on("MarkWebhookEventProcessed", returns(affected(1)))
+			db.on("MarkWebhookEventFailed", returns(affected(1)))
+
+			secret := tt.secret
+			if secret == "" {
+				secret = cfg.PolkaWebhookSecret
+			}
+			req := httptest.NewRequest("POST", "/api/polka/webhooks", strings.NewReader(tt.body))
+			req.Header.Set(polkaSignatureHeader, auth.SignWebhook(secret, []byte(tt.body), time.Now()))
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("POST /api/polka/webhooks = %d %s, want %d", w.Code, w.Body, tt.wantCode)
+			}
+
+			recorded := db.called("RecordWebhookEvent")
+			if tt.wantCode == http.StatusUnauthorized || tt.wantCode == http.StatusBadRequest {
+				if len(recorded) != 0 {
+					t.Error("an event that was not accepted was logged")
+				}
+				return
+			}
+			if len(recorded) != 1 || string(recorded[0].Args[3].(json.RawMessage)) != tt.body {
+				t.Errorf("logged %+v, want the raw body", recorded)
+			}
+
+			published := db.called("InsertOutboxEvent")
+			if tt.wantEvent != (len(published) == 1 && published[0].Committed) {
+				t.Errorf("published %+v, want an event %v", published, tt.wantEvent)
+			}
+			if tt.wantEvent && published[0].Args[1] != events.TypeUserUpgraded {
+				t.Errorf("published %v, want %s", published[0].Args[1], events.TypeUserUpgraded)
+			}
+
+			processed := db.called("MarkWebhookEventProcessed")
+			failures := db.called("MarkWebhookEventFailed")
+			switch tt.wantStatus {
+			case "processed":
+				if len(processed) != 1 || !processed[0].Committed || len(failures) != 0 {
+					t.Errorf("event marked processed %+v and failed %+v, want processed", processed, failures)
+				}
+			case "failed":
+				if len(failures) != 1 || !failures[0].Committed || len(processed) != 0 {
+					t.Fatalf("event marked processed %+v and failed %+v, want failed", processed, failures)
+				}
+				if reason := failures[0].Args[1].(sql.NullString); !reason.Valid {
+					t.Error("the failure was recorded without its reason")
+				}
+				for _, call := range db.called("StartSubscription") {
+					if call.Committed {
+						t.Error("the subscription of a failed event was kept")
+					}
+				}
+			default:
+				if len(processed) != 0 || len(failures) != 0 {
+					t.Errorf("event marked processed %+v and failed %+v, want it left alone", processed, failures)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+// Package mailer sends the transactional emails Chirpy needs, such as
+// password resets and address verification.
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Sends plain text mail through an SMTP relay
+type SMTP struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+func (m SMTP) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.Username != "" {
+		host := strings.Split(m.Addr, ":")[0]
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	return smtp.SendMail(m.Addr, auth, m.From, []string{msg.To}, format(m.From, msg))
+}
+
+// Writes each message to a file in Dir instead of sending it, for local
+// development
+type Dir struct {
+	Path string
+	From string
+}
+
+func (m Dir) Send(ctx context.Context, msg Message) error {
+	if err := os.MkdirAll(m.Path, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), strings.ReplaceAll(msg.To, "/", "_"))
+	return os.WriteFile(filepath.Join(m.Path, name), format(m.From, msg), 0o644)
+}
+
+// Keeps the most recent messages in memory and logs them, so links can
+// be copied from the server output
+type Memory struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+const memoryLimit = 100
+
+func (m *Memory) Send(ctx context.Context, msg Message) error {
+	log.Printf("mailer: to %s: %s\n%s", msg.To, msg.Subject, msg.Body)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.messages = append(m.messages, msg)
+	if len(m.messages) > memoryLimit {
+		m.messages = m.messages[len(m.messages)-memoryLimit:]
+	}
+	return nil
+}
+
+// Returns the messages kept so far
+func (m *Memory) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Message{}, m.messages...)
+}
+
+// Line breaks in a header value would let it inject headers of its own
+var headerValue = strings.NewReplacer("\r", "", "\n", "")
+
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue.Replace(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue.Replace(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue.Replace(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,54 @@
+package moderation
+
+import (
+	"regexp"
+)
+
+// Matches whole words against a list, comparing normalized forms so that
+// spellings like "K3rfuffl3" or "kérfuffle" are still caught
+type WordList struct {
+	words map[string]Action
+}
+
+func NewWordList(words map[string]Action) *WordList {
+	list := &WordList{words: map[string]Action{}}
+	for word, action := range words {
+		list.words[Normalize(word)] = action
+	}
+	return list
+}
+
+func (l *WordList) Check(body string) []Match {
+	matches := []Match{}
+	for _, token := range Tokenize(body) {
+		word := Normalize(token.Text)
+		if action, ok := l.words[word]; ok {
+			matches = append(matches, Match{
+				Rule:   "word:" + word,
+				Action: action,
+				Start:  token.Start,
+				End:    token.End,
+			})
+		}
+	}
+	return matches
+}
+
+type RegexRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+	Action  Action
+}
+
+func (rule RegexRule) Check(body string) []Match {
+	matches := []Match{}
+	for _, loc := range rule.Pattern.FindAllStringIndex(body, -1) {
+		matches = append(matches, Match{
+			Rule:   "regex:" + rule.Name,
+			Action: rule.Action,
+			Start:  loc[0],
+			End:    loc[1],
+		})
+	}
+	return matches
+}
@@ -0,0 +1,129 @@
+// Package moderation checks chirp bodies against configurable rules.
+//
+// A Chain runs a series of Filters over a body and combines what they
+// find: masked spans are replaced with ****, while the most severe action
+// decides whether the chirp is published, held for review or rejected.
+package moderation
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+type Action int
+
+// Actions in increasing order of severity
+const (
+	Allow Action = iota
+	Mask
+	Review
+	Reject
+)
+
+func (a Action) String() string {
+	switch a {
+	case Mask:
+		return "mask"
+	case Review:
+		return "review"
+	case Reject:
+		return "reject"
+	}
+	return "allow"
+}
+
+func ParseAction(s string) (Action, error) {
+	switch strings.ToLower(s) {
+	case "", "mask":
+		return Mask, nil
+	case "review", "hold":
+		return Review, nil
+	case "reject":
+		return Reject, nil
+	}
+	return Allow, errors.New("unknown moderation action " + s)
+}
+
+// A span of the body that broke a rule. Start and End are byte offsets.
+type Match struct {
+	Rule   string
+	Action Action
+	Start  int
+	End    int
+}
+
+type Filter interface {
+	Check(body string) []Match
+}
+
+type Result struct {
+	// The body with every masked span replaced
+	Body    string
+	Action  Action
+	Matches []Match
+}
+
+// Reasons lists the rules behind the result's action
+func (r Result) Reasons() []string {
+	reasons := []string{}
+	for _, match := range r.Matches {
+		if match.Action == r.Action {
+			reasons = append(reasons, match.Rule)
+		}
+	}
+	return reasons
+}
+
+type Chain struct {
+	filters []Filter
+}
+
+func NewChain(filters ...Filter) *Chain {
+	return &Chain{filters: filters}
+}
+
+func (c *Chain) Moderate(body string) Result {
+	result := Result{Body: body, Action: Allow}
+	for _, filter := range c.filters {
+		result.Matches = append(result.Matches, filter.Check(body)...)
+	}
+
+	masked := []Match{}
+	for _, match := range result.Matches {
+		if match.Action > result.Action {
+			result.Action = match.Action
+		}
+		if match.Action == Mask {
+			masked = append(masked, match)
+		}
+	}
+	result.Body = mask(body, masked)
+
+	return result
+}
+
+const maskText = "****"
+
+// Replaces the matched spans, merging any that overlap
+func mask(body string, matches []Match) string {
+	if len(matches) == 0 {
+		return body
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+
+	var b strings.Builder
+	pos := 0
+	for _, match := range matches {
+		if match.Start < pos {
+			// Overlaps the span that was just masked
+			pos = max(pos, match.End)
+			continue
+		}
+		b.WriteString(body[pos:match.Start])
+		b.WriteString(maskText)
+		pos = match.End
+	}
+	b.WriteString(body[pos:])
+	return b.String()
+}
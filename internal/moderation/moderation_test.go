@@ -0,0 +1,209 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"kerfuffle", "kerfuffle"},
+		{"KERFUFFLE", "kerfuffle"},
+		{"K3rfuffl3", "kerfuffle"},
+		{"kérfuffle", "kerfuffle"},
+		{"ｋｅｒｆｕｆｆｌｅ", "kerfuffle"},
+		{"$h@rbert", "sharbert"},
+		{"for-nax", "fornax"},
+		{"Straße", "strasse"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.text); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		body string
+		want []Token
+	}{
+		{"", []Token{}},
+		{"   ", []Token{}},
+		{"hello world", []Token{{"hello", 0, 5}, {"world", 6, 11}}},
+		{"Kerfuffle!", []Token{{"Kerfuffle", 0, 9}}},
+		{"(kerfuffle)", []Token{{"kerfuffle", 1, 10}}},
+		{"say $harbert", []Token{{"say", 0, 3}, {"$harbert", 4, 12}}},
+		{"... !!! ok.", []Token{{"ok", 8, 10}}},
+		{"tab\tand\nnewline", []Token{{"tab", 0, 3}, {"and", 4, 7}, {"newline", 8, 15}}},
+	}
+	for _, tt := range tests {
+		if got := Tokenize(tt.body); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Tokenize(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestParseAction(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Action
+		wantErr bool
+	}{
+		{"", Mask, false},
+		{"mask", Mask, false},
+		{"Review", Review, false},
+		{"hold", Review, false},
+		{"REJECT", Reject, false},
+		{"allow", Allow, true},
+		{"delete", Allow, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAction(tt.s)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseAction(%q) = %v, %v, want %v, error %v", tt.s, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestChainModerate(t *testing.T) {
+	chain := NewChain(
+		NewWordList(map[string]Action{
+			"kerfuffle": Mask,
+			"sharbert":  Mask,
+			"spam":      Review,
+		}),
+		RegexRule{Name: "followers", Pattern: regexp.MustCompile(`(?i)buy\s+followers`), Action: Reject},
+		RegexRule{Name: "fuffle", Pattern: regexp.MustCompile(`fuffle`), Action: Mask},
+	)
+
+	tests := []struct {
+		name        string
+		body        string
+		wantBody    string
+		wantAction  Action
+		wantReasons []string
+	}{
+		{
+			name:        "clean",
+			body:        "This is a nice chirp",
+			wantBody:    "This is a nice chirp",
+			wantAction:  Allow,
+			wantReasons: []string{},
+		},
+		{
+			name:        "masks words",
+			body:        "What a kerfuffle, said the Sharbert!",
+			wantBody:    "What a ****, said the ****!",
+			wantAction:  Mask,
+			wantReasons: []string{"word:kerfuffle", "word:sharbert", "regex:fuffle"},
+		},
+		{
+			name:        "masks disguised words",
+			body:        "K3rfuffl3 and $h@rbert",
+			wantBody:    "**** and ****",
+			wantAction:  Mask,
+			wantReasons: []string{"word:kerfuffle", "word:sharbert"},
+		},
+		{
+			name:        "merges overlapping masks",
+			body:        "a kerfuffle here",
+			wantBody:    "a **** here",
+			wantAction:  Mask,
+			wantReasons: []string{"word:kerfuffle", "regex:fuffle"},
+		},
+		{
+			name:        "most severe action wins",
+			body:        "spam about a kerfuffle",
+			wantBody:    "spam about a ****",
+			wantAction:  Review,
+			wantReasons: []string{"word:spam"},
+		},
+		{
+			name:        "rejects patterns",
+			body:        "Buy   followers now",
+			wantBody:    "Buy   followers now",
+			wantAction:  Reject,
+			wantReasons: []string{"regex:followers"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := chain.Moderate(tt.body)
+			if result.Body != tt.wantBody {
+				t.Errorf("Body = %q, want %q", result.Body, tt.wantBody)
+			}
+			if result.Action != tt.wantAction {
+				t.Errorf("Action = %v, want %v", result.Action, tt.wantAction)
+			}
+			if reasons := result.Reasons(); !reflect.DeepEqual(reasons, tt.wantReasons) {
+				t.Errorf("Reasons() = %v, want %v", reasons, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestDefaultChain(t *testing.T) {
+	result := DefaultChain().Moderate("I had something interesting for breakfast, a Fornax")
+	if want := "I had something interesting for breakfast, a ****"; result.Body != want {
+		t.Errorf("Body = %q, want %q", result.Body, want)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	tests := []struct {
+		name       string
+		rules      string
+		body       string
+		wantAction Action
+		wantErr    bool
+	}{
+		{
+			name:       "words",
+			rules:      `{"words": {"mask": ["kerfuffle"], "reject": ["fornax"]}}`,
+			body:       "a fornax",
+			wantAction: Reject,
+		},
+		{
+			name:       "a word listed twice keeps the most severe action",
+			rules:      `{"words": {"reject": ["fornax"], "mask": ["fornax"]}}`,
+			body:       "a fornax",
+			wantAction: Reject,
+		},
+		{
+			name:       "patterns",
+			rules:      `{"patterns": [{"name": "links", "pattern": "https?://", "action": "review"}]}`,
+			body:       "see http://example.com",
+			wantAction: Review,
+		},
+		{name: "invalid json", rules: `{"words": `, wantErr: true},
+		{name: "unknown action", rules: `{"words": {"delete": ["fornax"]}}`, wantErr: true},
+		{name: "invalid pattern", rules: `{"patterns": [{"name": "bad", "pattern": "("}]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "rules.json")
+			if err := os.WriteFile(path, []byte(tt.rules), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			chain, err := LoadFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadFile() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := chain.Moderate(tt.body).Action; got != tt.wantAction {
+				t.Errorf("Action = %v, want %v", got, tt.wantAction)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// A whitespace separated word of a body. Start and End are byte offsets of
+// the word with any surrounding punctuation trimmed off.
+type Token struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// Splits body on whitespace and trims the punctuation around each word,
+// so "Kerfuffle!" and "(kerfuffle)" both yield "Kerfuffle"
+func Tokenize(body string) []Token {
+	tokens := []Token{}
+	start := -1
+	for i, c := range body + " " {
+		if !unicode.IsSpace(c) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			if token, ok := trimToken(body, start, i); ok {
+				tokens = append(tokens, token)
+			}
+			start = -1
+		}
+	}
+	return tokens
+}
+
+func trimToken(body string, start, end int) (Token, bool) {
+	for start < end {
+		c, size := utf8.DecodeRuneInString(body[start:end])
+		if isWordRune(c) || leet[c] != 0 {
+			break
+		}
+		start += size
+	}
+	for end > start {
+		c, size := utf8.DecodeLastRuneInString(body[start:end])
+		if isWordRune(c) {
+			break
+		}
+		end -= size
+	}
+	if start == end {
+		return Token{}, false
+	}
+	return Token{Text: body[start:end], Start: start, End: end}, true
+}
+
+func isWordRune(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// Characters commonly substituted for letters to dodge filters
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'8': 'b',
+	'@': 'a',
+	'$': 's',
+	'!': 'i',
+	'|': 'l',
+	'+': 't',
+}
+
+var folder = cases.Fold()
+
+// Reduces text to a canonical form for matching: compatibility
+// decomposition with accents dropped, Unicode case folding, leetspeak
+// substitutions undone and any remaining non-letters removed.
+func Normalize(text string) string {
+	decomposed := norm.NFKD.String(folder.String(text))
+
+	var b strings.Builder
+	for _, c := range decomposed {
+		if unicode.Is(unicode.Mn, c) {
+			continue
+		}
+		if sub, ok := leet[c]; ok {
+			c = sub
+		}
+		if unicode.IsLetter(c) {
+			b.WriteRune(c)
+		}
+	}
+	return norm.NFC.String(b.String())
+}
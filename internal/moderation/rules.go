@@ -0,0 +1,142 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"regexp"
+	"sync/atomic"
+	"time"
+)
+
+// Rules file format:
+//
+//	{
+//	  "words": {
+//	    "mask": ["kerfuffle", "sharbert", "fornax"],
+//	    "review": ["..."],
+//	    "reject": ["..."]
+//	  },
+//	  "patterns": [
+//	    {"name": "follower-spam", "pattern": "(?i)buy\\s+followers", "action": "reject"}
+//	  ]
+//	}
+type rulesFile struct {
+	Words    map[string][]string `json:"words"`
+	Patterns []struct {
+		Name    string `json:"name"`
+		Pattern string `json:"pattern"`
+		Action  string `json:"action"`
+	} `json:"patterns"`
+}
+
+// The word list Chirpy has always masked
+func DefaultChain() *Chain {
+	return NewChain(NewWordList(map[string]Action{
+		"kerfuffle": Mask,
+		"sharbert":  Mask,
+		"fornax":    Mask,
+	}))
+}
+
+// Builds a chain from a JSON rules file
+func LoadFile(path string) (*Chain, error) {
+	dat, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := rulesFile{}
+	if err := json.Unmarshal(dat, &rules); err != nil {
+		return nil, err
+	}
+
+	words := map[string]Action{}
+	for actionName, list := range rules.Words {
+		action, err := ParseAction(actionName)
+		if err != nil {
+			return nil, err
+		}
+		for _, word := range list {
+			if action > words[word] {
+				words[word] = action
+			}
+		}
+	}
+
+	filters := []Filter{NewWordList(words)}
+	for _, pattern := range rules.Patterns {
+		action, err := ParseAction(pattern.Action)
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, RegexRule{Name: pattern.Name, Pattern: re, Action: action})
+	}
+
+	return NewChain(filters...), nil
+}
+
+// Holds the active chain and swaps it out whenever the rules file changes,
+// so rules can be edited without restarting the server
+type Moderator struct {
+	chain atomic.Pointer[Chain]
+}
+
+func NewModerator(chain *Chain) *Moderator {
+	m := &Moderator{}
+	m.chain.Store(chain)
+	return m
+}
+
+func (m *Moderator) Moderate(body string) Result {
+	return m.chain.Load().Moderate(body)
+}
+
+// Loads the rules file and keeps polling it for changes until ctx is done.
+// A rules file that fails to load keeps the previous rules in place.
+func Watch(ctx context.Context, path string, interval time.Duration) (*Moderator, error) {
+	chain, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	m := NewModerator(chain)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	modTime := info.ModTime()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().Equal(modTime) {
+				continue
+			}
+			modTime = info.ModTime()
+
+			chain, err := LoadFile(path)
+			if err != nil {
+				log.Printf("moderation: keeping previous rules, reload of %s failed: %v", path, err)
+				continue
+			}
+			m.chain.Store(chain)
+			log.Printf("moderation: reloaded rules from %s", path)
+		}
+	}()
+
+	return m, nil
+}
@@ -0,0 +1,89 @@
+// Package notifications tells users when others reply to, mention, like
+// or rechirp their chirps. Notifications are made from events on the
+// event bus, so the requests causing them do not wait for it.
+package notifications
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+// Notification types, which users can mute one by one
+const (
+	Reply   = "reply"
+	Mention = "mention"
+	Like    = "like"
+	Rechirp = "rechirp"
+)
+
+var Types = []string{Reply, Mention, Like, Rechirp}
+
+// Subscribes to the event bus. Events handed over twice notify once,
+// notifications are unique by what they say.
+func Subscribe(bus *events.Bus, queries *database.Queries) {
+	bus.Subscribe("notifications", func(ctx context.Context, event events.Envelope) error {
+		return notify(ctx, queries, event.Event)
+	})
+}
+
+func notify(ctx context.Context, queries *database.Queries, event events.Event) error {
+	switch e := event.(type) {
+	case events.ChirpCreated:
+		return chirpCreated(ctx, queries, e)
+	case events.ChirpLiked:
+		return create(ctx, queries, e.AuthorID, Like, e.UserID, e.ChirpID)
+	case events.ChirpRechirped:
+		return create(ctx, queries, e.AuthorID, Rechirp, e.UserID, e.ChirpID)
+	}
+	return nil
+}
+
+// Notifies the author of the chirp replied to and everyone mentioned,
+// except a replied to author who is also mentioned
+func chirpCreated(ctx context.Context, queries *database.Queries, e events.ChirpCreated) error {
+	repliedTo := uuid.Nil
+	if e.InReplyTo != nil {
+		parent, err := queries.GetChirpById(ctx, *e.InReplyTo)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		// The parent may have been deleted since
+		if err == nil {
+			repliedTo = parent.UserID
+			err = create(ctx, queries, repliedTo, Reply, e.UserID, e.ID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	mentions, err := queries.GetChirpMentions(ctx, []uuid.UUID{e.ID})
+	if err != nil {
+		return err
+	}
+	for _, mention := range mentions {
+		if !mention.UserID.Valid || mention.UserID.UUID == repliedTo {
+			continue
+		}
+		err = create(ctx, queries, mention.UserID.UUID, Mention, e.UserID, e.ID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Users are not notified of what they did themselves, nor of types they
+// muted. Nothing is made when the chirp or actor was deleted meanwhile.
+func create(ctx context.Context, queries *database.Queries, userID uuid.UUID, notificationType string, actorID, chirpID uuid.UUID) error {
+	return queries.CreateNotification(ctx, database.CreateNotificationParams{
+		Type:    notificationType,
+		ActorID: actorID,
+		ChirpID: chirpID,
+		UserID:  userID,
+	})
+}
@@ -0,0 +1,109 @@
+// Package ratelimit limits how often clients may call the API with token
+// buckets: each client's bucket holds up to Burst requests and refills at
+// an even pace, so it is full again Period after it was emptied.
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+func PerMinute(n int) Limit {
+	return Limit{Burst: n, Period: time.Minute}
+}
+
+// The limit with factor times the quota over the same period
+func (l Limit) Scale(factor int) Limit {
+	return Limit{Burst: l.Burst * factor, Period: l.Period}
+}
+
+// Describes the limit for the RateLimit-Policy header, e.g. "100;w=60"
+func (l Limit) Policy() string {
+	return strconv.Itoa(l.Burst) + ";w=" + strconv.Itoa(int(l.Period.Seconds()))
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Until the bucket is full again
+	Reset time.Duration
+	// Until the next request will be allowed, when this one was not
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration
+}
+
+// Buckets kept in memory, so each server instance limits on its own
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+}
+
+func New() *Limiter {
+	return &Limiter{buckets: map[string]*bucket{}}
+}
+
+// Takes a token from key's bucket if there is one
+func (l *Limiter) Allow(key string, limit Limit) Result {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.prune(now)
+
+	rate := limit.rate()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+	b.period = limit.Period
+
+	result := Result{Limit: limit.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((float64(limit.Burst) - b.tokens) / rate)
+	return result
+}
+
+// Forgets buckets that have had time to refill, as they are no different
+// from new ones
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < time.Minute {
+		return
+	}
+	for key, b := range l.buckets {
+		if now.Sub(b.updated) >= b.period {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastPrune = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
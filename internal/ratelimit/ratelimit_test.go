@@ -0,0 +1,96 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimitPolicy(t *testing.T) {
+	tests := []struct {
+		limit Limit
+		want  string
+	}{
+		{PerMinute(100), "100;w=60"},
+		{PerMinute(10).Scale(3), "30;w=60"},
+		{Limit{Burst: 5, Period: time.Hour}, "5;w=3600"},
+	}
+	for _, tt := range tests {
+		if got := tt.limit.Policy(); got != tt.want {
+			t.Errorf("%+v.Policy() = %q, want %q", tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestLimiterAllow(t *testing.T) {
+	limiter := New()
+	limit := Limit{Burst: 3, Period: time.Hour}
+
+	tests := []struct {
+		allowed   bool
+		remaining int
+	}{
+		{true, 2},
+		{true, 1},
+		{true, 0},
+		{false, 0},
+		{false, 0},
+	}
+	for i, tt := range tests {
+		result := limiter.Allow("client", limit)
+		if result.Allowed != tt.allowed || result.Remaining != tt.remaining || result.Limit != 3 {
+			t.Errorf("request %d: Allow() = %+v, want allowed %v with %d remaining of 3", i+1, result, tt.allowed, tt.remaining)
+		}
+		if result.Allowed && result.RetryAfter != 0 {
+			t.Errorf("request %d: RetryAfter = %s on an allowed request", i+1, result.RetryAfter)
+		}
+	}
+
+	// An empty bucket gains a token every Period / Burst
+	result := limiter.Allow("client", limit)
+	if result.RetryAfter <= 19*time.Minute || result.RetryAfter > 20*time.Minute {
+		t.Errorf("RetryAfter = %s, want just under 20m", result.RetryAfter)
+	}
+	if result.Reset <= 59*time.Minute || result.Reset > time.Hour {
+		t.Errorf("Reset = %s, want just under 1h", result.Reset)
+	}
+
+	// Keys have buckets of their own
+	if result := limiter.Allow("other", limit); !result.Allowed || result.Remaining != 2 {
+		t.Errorf("Allow() for another key = %+v, want allowed with 2 remaining", result)
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	limiter := New()
+	limit := Limit{Burst: 2, Period: 100 * time.Millisecond}
+
+	for range 2 {
+		limiter.Allow("client", limit)
+	}
+	if result := limiter.Allow("client", limit); result.Allowed {
+		t.Fatalf("Allow() on an empty bucket = %+v, want refused", result)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if result := limiter.Allow("client", limit); !result.Allowed {
+		t.Errorf("Allow() after a token refilled = %+v, want allowed", result)
+	}
+}
+
+func TestLimiterPrune(t *testing.T) {
+	limiter := New()
+	limit := Limit{Burst: 1, Period: time.Millisecond}
+	limiter.Allow("idle", limit)
+
+	// Pruning happens at most once a minute
+	limiter.lastPrune = time.Now().Add(-2 * time.Minute)
+	time.Sleep(2 * time.Millisecond)
+	limiter.Allow("active", PerMinute(10))
+
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Error("the bucket of an idle client was kept after it refilled")
+	}
+	if _, ok := limiter.buckets["active"]; !ok {
+		t.Error("the bucket of the active client was dropped")
+	}
+}
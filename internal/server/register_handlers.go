@@ -5,7 +5,8 @@ import (
 	"net/http"
 )
 
-func RegisterHandlers(s *http.ServeMux, apiConfig *handlers.ApiConfig) {
+// Adds every route to the mux, returning the handler to serve with
+func RegisterHandlers(s *http.ServeMux, apiConfig *handlers.ApiConfig) http.Handler {
 
 	handlers := []func(*http.ServeMux, *handlers.ApiConfig){
 		handlers.UserRoutes,
@@ -14,10 +15,25 @@ func RegisterHandlers(s *http.ServeMux, apiConfig *handlers.ApiConfig) {
 		handlers.MetricsRoutes,
 		handlers.TokenRoutes,
 		handlers.WebhookRoutes,
+		handlers.FollowRoutes,
+		handlers.ThreadRoutes,
+		handlers.EngagementRoutes,
+		handlers.EntityRoutes,
+		handlers.ModerationRoutes,
+		handlers.SessionRoutes,
+		handlers.JWKSRoutes,
+		handlers.TwoFactorRoutes,
+		handlers.AccountRoutes,
+		handlers.AccessTokenRoutes,
+		handlers.OAuthRoutes,
+		handlers.OutgoingWebhookRoutes,
+		handlers.StreamRoutes,
+		handlers.NotificationRoutes,
 	}
 
 	for _, handler := range handlers {
 		handler(s, apiConfig)
 	}
 
+	return apiConfig.MiddlewareRateLimit(s)
 }
@@ -1,42 +1,266 @@
 package server
 
 import (
+	"chirpy/internal/auth"
 	"chirpy/internal/database"
+	"chirpy/internal/events"
 	"chirpy/internal/handlers"
+	"chirpy/internal/mailer"
+	"chirpy/internal/moderation"
+	"chirpy/internal/notifications"
+	"chirpy/internal/ratelimit"
+	"chirpy/internal/stream"
+	"chirpy/internal/throttle"
+	"chirpy/internal/webhooks"
+	"context"
 	"database/sql"
+	"errors"
+	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"time"
 
 	_ "github.com/lib/pq"
 
 	"github.com/joho/godotenv"
 )
 
-func StartApp(address string) {
-
+func openDB() *sql.DB {
 	godotenv.Load()
 
-	dbURL := os.Getenv("DB_URL")
-
-	db, err := sql.Open("postgres", dbURL)
+	db, err := sql.Open("postgres", os.Getenv("DB_URL"))
 	if err != nil {
 		os.Exit(1)
 	}
+	return db
+}
+
+// Grants the admin role to an existing account, used to bootstrap the
+// first admin before anyone can call PUT /admin/users/{userID}/role
+func PromoteAdmin(email string) error {
+	db := openDB()
+	defer db.Close()
+
+	_, err := database.New(db).SetUserRoleByEmail(context.Background(), database.SetUserRoleByEmailParams{
+		Role:  string(auth.RoleAdmin),
+		Email: email,
+	})
+	if err == sql.ErrNoRows {
+		return errors.New("no user with email " + email)
+	}
+	return err
+}
+
+// Signs with the keys in JWT_KEYS_DIR when it is set, otherwise falls
+// back to HS256 with SECRET. When both are set SECRET only verifies, so
+// tokens issued before the switch to key files stay valid until expiry.
+func loadKeys() (*auth.KeySet, error) {
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" {
+		issuer = "chirpy"
+	}
+	audience := os.Getenv("JWT_AUDIENCE")
+	if audience == "" {
+		audience = "chirpy-api"
+	}
+
+	secret := os.Getenv("SECRET")
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		keys, err := auth.LoadKeySet(dir, os.Getenv("JWT_SIGNING_KID"), issuer, audience)
+		if err != nil || secret == "" {
+			return keys, err
+		}
+		return keys, keys.Add(auth.NewHMACKey("secret", []byte(secret)), false)
+	}
+
+	if secret == "" {
+		return nil, errors.New("set JWT_KEYS_DIR or SECRET")
+	}
+	keys := auth.NewKeySet(issuer, audience)
+	return keys, keys.Add(auth.NewHMACKey("secret", []byte(secret)), true)
+}
+
+// Password hashing costs can be raised with ARGON2_MEMORY_KIB,
+// ARGON2_ITERATIONS and ARGON2_PARALLELISM
+func argon2ParamsFromEnv() (auth.Argon2Params, error) {
+	params := auth.DefaultArgon2Params
+	settings := []struct {
+		env   string
+		value *uint32
+	}{
+		{"ARGON2_MEMORY_KIB", &params.Memory},
+		{"ARGON2_ITERATIONS", &params.Iterations},
+	}
+	for _, setting := range settings {
+		if v := os.Getenv(setting.env); v != "" {
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil || n == 0 {
+				return params, errors.New(setting.env + " must be a positive number")
+			}
+			*setting.value = uint32(n)
+		}
+	}
+	if v := os.Getenv("ARGON2_PARALLELISM"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 8)
+		if err != nil || n == 0 {
+			return params, errors.New("ARGON2_PARALLELISM must be between 1 and 255")
+		}
+		params.Parallelism = uint8(n)
+	}
+	return params, nil
+}
+
+// Picks the mailer from MAILER: smtp, file (writes to MAIL_DIR) or the
+// default, memory, which logs messages instead of sending them
+func newMailer() mailer.Mailer {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "Chirpy <no-reply@chirpy.local>"
+	}
+
+	switch os.Getenv("MAILER") {
+	case "smtp":
+		return mailer.SMTP{
+			Addr:     os.Getenv("SMTP_ADDR"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     from,
+		}
+	case "file":
+		// Not under the working directory, which /app/ serves publicly
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "chirpy-mail")
+		}
+		return mailer.Dir{Path: dir, From: from}
+	}
+	return &mailer.Memory{}
+}
+
+// Requests per minute for each route group can be set with
+// RATE_LIMIT_AUTH, RATE_LIMIT_WRITE and RATE_LIMIT_READ, 0 turning the
+// group's limit off, and the Chirpy Red bonus with RATE_LIMIT_RED_MULTIPLIER
+func rateLimitsFromEnv() (handlers.RateLimits, error) {
+	limits := handlers.DefaultRateLimits
+	settings := []struct {
+		env   string
+		limit *ratelimit.Limit
+	}{
+		{"RATE_LIMIT_AUTH", &limits.Auth},
+		{"RATE_LIMIT_WRITE", &limits.Write},
+		{"RATE_LIMIT_READ", &limits.Read},
+	}
+	for _, setting := range settings {
+		if v := os.Getenv(setting.env); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return limits, errors.New(setting.env + " must be a number of requests per minute")
+			}
+			*setting.limit = ratelimit.PerMinute(n)
+		}
+	}
+	if v := os.Getenv("RATE_LIMIT_RED_MULTIPLIER"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return limits, errors.New("RATE_LIMIT_RED_MULTIPLIER must be a positive number")
+		}
+		limits.RedMultiplier = n
+	}
+	return limits, nil
+}
+
+// Counts failed logins in Postgres so every instance sees them, unless
+// LOGIN_THROTTLE_STORE is memory
+func newLoginThrottle(queries *database.Queries) (*handlers.LoginThrottle, error) {
+	switch os.Getenv("LOGIN_THROTTLE_STORE") {
+	case "", "postgres":
+		return handlers.NewLoginThrottle(throttle.NewPostgresStore(queries)), nil
+	case "memory":
+		return handlers.NewLoginThrottle(throttle.NewMemoryStore()), nil
+	}
+	return nil, errors.New("LOGIN_THROTTLE_STORE must be postgres or memory")
+}
+
+func StartApp(address string) {
+
+	db := openDB()
 	dbQueries := database.New(db)
+	var err error
+
+	moderator := moderation.NewModerator(moderation.DefaultChain())
+	if rulesPath := os.Getenv("MODERATION_RULES"); rulesPath != "" {
+		moderator, err = moderation.Watch(context.Background(), rulesPath, 10*time.Second)
+		if err != nil {
+			log.Fatalf("loading moderation rules: %v", err)
+		}
+	}
+
+	keys, err := loadKeys()
+	if err != nil {
+		log.Fatalf("loading JWT keys: %v", err)
+	}
+
+	argon2Params, err := argon2ParamsFromEnv()
+	if err != nil {
+		log.Fatalf("reading argon2 parameters: %v", err)
+	}
+	auth.SetArgon2Params(argon2Params)
+
+	loginThrottle, err := newLoginThrottle(dbQueries)
+	if err != nil {
+		log.Fatalf("configuring login throttling: %v", err)
+	}
+
+	rateLimits, err := rateLimitsFromEnv()
+	if err != nil {
+		log.Fatalf("reading rate limits: %v", err)
+	}
+
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost" + address
+	}
+
+	// Every server streams events from every server, woken by Postgres
+	notified, err := events.Listen(context.Background(), os.Getenv("DB_URL"))
+	if err != nil {
+		log.Fatalf("listening for events: %v", err)
+	}
+	hub := stream.NewHub(dbQueries)
+	go hub.Run(context.Background(), notified, 5*time.Second)
 
 	serveMux := http.NewServeMux()
 
 	apiConfig := &handlers.ApiConfig{
-		DbQueries: dbQueries,
-		JwtSecret: os.Getenv("SECRET"),
-		PolkaKey:  os.Getenv("POLKA_API_KEY"),
+		DB:                 db,
+		DbQueries:          dbQueries,
+		Moderator:          moderator,
+		Keys:               keys,
+		PolkaWebhookSecret: os.Getenv("POLKA_WEBHOOK_SECRET"),
+		Mailer:             newMailer(),
+		AppURL:             appURL,
+
+		RequireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true",
+		LoginThrottle:        loginThrottle,
+		RateLimiter:          ratelimit.New(),
+		RateLimits:           rateLimits,
+		Stream:               hub,
 	}
 
-	RegisterHandlers(serveMux, apiConfig)
+	handler := RegisterHandlers(serveMux, apiConfig)
+
+	// Handlers write events to the outbox, the bus hands them on
+	bus := events.NewBus(db, dbQueries)
+	webhooks.Subscribe(bus, dbQueries)
+	notifications.Subscribe(bus, dbQueries)
+	go bus.Run(context.Background(), time.Second)
+	go webhooks.NewDispatcher(dbQueries).Run(context.Background(), 5*time.Second)
 
 	server := http.Server{
 		Addr:    address,
-		Handler: serveMux,
+		Handler: handler,
 	}
 
 	server.ListenAndServe()
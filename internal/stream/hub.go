@@ -0,0 +1,113 @@
+// Package stream fans events out to the clients connected to this server.
+// Every server reads the outbox itself when Postgres notifies it of a
+// commit, so clients see events from every server.
+package stream
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	batchSize = 100
+	// Events a client may fall behind by before it is dropped
+	bufferSize = 256
+)
+
+// A client's feed of events. C is closed when the client falls too far
+// behind, it should reconnect and resume from the last event it got.
+type Subscription struct {
+	C chan events.Envelope
+}
+
+type Hub struct {
+	queries *database.Queries
+
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+func NewHub(queries *database.Queries) *Hub {
+	return &Hub{queries: queries, subscribers: map[*Subscription]struct{}{}}
+}
+
+func (h *Hub) Subscribe() *Subscription {
+	sub := &Subscription{C: make(chan events.Envelope, bufferSize)}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.subscribers[sub] = struct{}{}
+	return sub
+}
+
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.subscribers[sub]; ok {
+		delete(h.subscribers, sub)
+		close(sub.C)
+	}
+}
+
+func (h *Hub) broadcast(event events.Envelope) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		select {
+		case sub.C <- event:
+		default:
+			delete(h.subscribers, sub)
+			close(sub.C)
+		}
+	}
+}
+
+// Events committed since the position, for clients resuming a stream
+func (h *Hub) Backlog(ctx context.Context, after events.Position) ([]events.Envelope, error) {
+	return events.Read(ctx, h.queries, after, batchSize)
+}
+
+// Reads new events from the outbox when woken, and every interval in case
+// an event only became readable after its notification, until ctx is done
+func (h *Hub) Run(ctx context.Context, wake <-chan struct{}, interval time.Duration) {
+	position, err := events.Latest(ctx, h.queries)
+	for err != nil {
+		log.Printf("stream: finding latest event: %v", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		position, err = events.Latest(ctx, h.queries)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-ticker.C:
+		}
+
+		for {
+			batch, err := events.Read(ctx, h.queries, position, batchSize)
+			if err != nil {
+				log.Printf("stream: reading events: %v", err)
+				break
+			}
+			for _, event := range batch {
+				h.broadcast(event)
+				position = event.Position
+			}
+			if len(batch) < batchSize {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Keeps counts in the server's memory, so each instance limits on its own
+// and restarts forget every failure
+type MemoryStore struct {
+	mu        sync.Mutex
+	attempts  map[string]Attempts
+	lastPrune time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{attempts: map[string]Attempts{}}
+}
+
+func (s *MemoryStore) Fail(ctx context.Context, key string, now, windowStart time.Time) (Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Drop keys that have gone quiet now and then so the map stays small
+	if now.Sub(s.lastPrune) > time.Minute {
+		for k, attempts := range s.attempts {
+			if attempts.LastFailure.Before(windowStart) {
+				delete(s.attempts, k)
+			}
+		}
+		s.lastPrune = now
+	}
+
+	attempts := s.attempts[key]
+	if attempts.LastFailure.Before(windowStart) {
+		attempts.Failures = 0
+	}
+	attempts.Failures++
+	attempts.LastFailure = now
+	s.attempts[key] = attempts
+	return attempts, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (Attempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts[key], nil
+}
+
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if attempts, ok := s.attempts[key]; ok && attempts.Failures > 0 {
+		attempts.Failures--
+		s.attempts[key] = attempts
+	}
+	return nil
+}
+
+func (s *MemoryStore) Reset(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	return nil
+}
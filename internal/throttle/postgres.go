@@ -0,0 +1,69 @@
+package throttle
+
+import (
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Keeps counts in the login_attempts table, shared by every instance
+type PostgresStore struct {
+	queries   *database.Queries
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func NewPostgresStore(queries *database.Queries) *PostgresStore {
+	return &PostgresStore{queries: queries}
+}
+
+// Times are stored as UTC, the column has no time zone
+func (s *PostgresStore) Fail(ctx context.Context, key string, now, windowStart time.Time) (Attempts, error) {
+	if s.shouldPrune(now) {
+		err := s.queries.DeleteStaleLoginAttempts(ctx, windowStart.UTC())
+		if err != nil {
+			return Attempts{}, err
+		}
+	}
+
+	row, err := s.queries.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Key:           key,
+		LastFailureAt: now.UTC(),
+		WindowStart:   windowStart.UTC(),
+	})
+	if err != nil {
+		return Attempts{}, err
+	}
+	return Attempts{Failures: int(row.Failures), LastFailure: row.LastFailureAt}, nil
+}
+
+func (s *PostgresStore) shouldPrune(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now.Sub(s.lastPrune) < time.Minute {
+		return false
+	}
+	s.lastPrune = now
+	return true
+}
+
+func (s *PostgresStore) Get(ctx context.Context, key string) (Attempts, error) {
+	row, err := s.queries.GetLoginAttempts(ctx, key)
+	if err == sql.ErrNoRows {
+		return Attempts{}, nil
+	}
+	if err != nil {
+		return Attempts{}, err
+	}
+	return Attempts{Failures: int(row.Failures), LastFailure: row.LastFailureAt}, nil
+}
+
+func (s *PostgresStore) Release(ctx context.Context, key string) error {
+	return s.queries.ReleaseLoginFailure(ctx, key)
+}
+
+func (s *PostgresStore) Reset(ctx context.Context, key string) error {
+	return s.queries.DeleteLoginAttempts(ctx, key)
+}
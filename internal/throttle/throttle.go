@@ -0,0 +1,126 @@
+// Package throttle slows down password guessing by counting failed
+// attempts per key, such as an account or a client IP.
+//
+// After a few free failures every further one makes the key wait twice as
+// long before it may try again, and once enough have piled up the key is
+// locked out for a while. Each attempt is counted as a failure before it
+// is made and released once it succeeds, so attempts made in parallel
+// cannot all go ahead on the same count. A success resets the count.
+package throttle
+
+import (
+	"context"
+	"time"
+)
+
+// The failures counted for a key
+type Attempts struct {
+	Failures    int
+	LastFailure time.Time
+}
+
+// Where failures are counted. Implementations must be safe for concurrent
+// use; a shared store makes the limits hold across server instances.
+type Store interface {
+	// Counts a failure at now, starting over from one when the previous
+	// failure was before windowStart
+	Fail(ctx context.Context, key string, now, windowStart time.Time) (Attempts, error)
+	// Returns zero Attempts for keys without failures
+	Get(ctx context.Context, key string) (Attempts, error)
+	// Takes back one failure counted by Fail
+	Release(ctx context.Context, key string) error
+	Reset(ctx context.Context, key string) error
+}
+
+type Policy struct {
+	// Failures allowed before any delay is imposed
+	FreeAttempts int
+	// The delay after the first failure past the free ones, doubling with
+	// each further failure up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Failures after which the key is locked out for LockoutDuration
+	// following every further failure
+	LockoutThreshold int
+	LockoutDuration  time.Duration
+	// Failures are forgotten once none has happened for this long
+	Window time.Duration
+}
+
+// Whether an attempt may go ahead
+type Decision struct {
+	// Zero when the attempt may go ahead
+	RetryAfter time.Duration
+	// Set once the key has failed often enough to be locked out
+	Locked bool
+}
+
+func (d Decision) Allowed() bool {
+	return d.RetryAfter <= 0
+}
+
+func (p Policy) decide(attempts Attempts, now time.Time) Decision {
+	if attempts.Failures <= p.FreeAttempts || now.Sub(attempts.LastFailure) > p.Window {
+		return Decision{}
+	}
+
+	locked := p.LockoutThreshold > 0 && attempts.Failures >= p.LockoutThreshold
+	delay := p.LockoutDuration
+	if !locked {
+		delay = p.BaseDelay
+		for i := p.FreeAttempts + 1; i < attempts.Failures && delay < p.MaxDelay; i++ {
+			delay *= 2
+		}
+		delay = min(delay, p.MaxDelay)
+	}
+
+	return Decision{RetryAfter: attempts.LastFailure.Add(delay).Sub(now), Locked: locked}
+}
+
+type Throttler struct {
+	store  Store
+	policy Policy
+}
+
+func New(store Store, policy Policy) *Throttler {
+	return &Throttler{store: store, policy: policy}
+}
+
+// Tells whether key may make an attempt now and, when it may, counts the
+// attempt as failed until Release is called. Attempts that have to wait
+// are not counted.
+func (t *Throttler) Attempt(ctx context.Context, key string) (Decision, error) {
+	before, err := t.store.Get(ctx, key)
+	if err != nil {
+		return Decision{}, err
+	}
+	now := time.Now().UTC()
+	if decision := t.policy.decide(before, now); !decision.Allowed() {
+		return decision, nil
+	}
+
+	reserved, err := t.store.Fail(ctx, key, now, now.Add(-t.policy.Window))
+	if err != nil {
+		return Decision{}, err
+	}
+
+	// Other attempts were counted since the check. Judge this one as if
+	// the last of them had already failed.
+	if reserved.Failures > before.Failures+1 {
+		decision := t.policy.decide(Attempts{Failures: reserved.Failures - 1, LastFailure: now}, now)
+		if !decision.Allowed() {
+			return decision, t.store.Release(ctx, key)
+		}
+	}
+	return Decision{}, nil
+}
+
+// Takes back an attempt counted by Attempt once it has succeeded
+func (t *Throttler) Release(ctx context.Context, key string) error {
+	return t.store.Release(ctx, key)
+}
+
+// Forgets the failures of key, after a success or to unlock it
+func (t *Throttler) Reset(ctx context.Context, key string) error {
+	return t.store.Reset(ctx, key)
+}
@@ -0,0 +1,186 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	FreeAttempts:     3,
+	BaseDelay:        time.Second,
+	MaxDelay:         10 * time.Second,
+	LockoutThreshold: 10,
+	LockoutDuration:  15 * time.Minute,
+	Window:           24 * time.Hour,
+}
+
+func TestPolicyDecide(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		failures int
+		ago      time.Duration
+		want     Decision
+	}{
+		{"no failures", 0, 0, Decision{}},
+		{"free attempts", 3, 0, Decision{}},
+		{"first delay", 4, 0, Decision{RetryAfter: time.Second}},
+		{"doubles", 5, 0, Decision{RetryAfter: 2 * time.Second}},
+		{"doubles again", 6, 0, Decision{RetryAfter: 4 * time.Second}},
+		{"capped", 9, 0, Decision{RetryAfter: 10 * time.Second}},
+		{"partly waited", 5, 500 * time.Millisecond, Decision{RetryAfter: 1500 * time.Millisecond}},
+		{"waited out", 5, 2 * time.Second, Decision{}},
+		{"locked", 10, 0, Decision{RetryAfter: 15 * time.Minute, Locked: true}},
+		{"still locked", 12, 5 * time.Minute, Decision{RetryAfter: 10 * time.Minute, Locked: true}},
+		{"outside the window", 50, 25 * time.Hour, Decision{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := testPolicy.decide(Attempts{Failures: tt.failures, LastFailure: now.Add(-tt.ago)}, now)
+			if got != tt.want {
+				t.Errorf("decide() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	noLockout := testPolicy
+	noLockout.LockoutThreshold = 0
+	if got := noLockout.decide(Attempts{Failures: 100, LastFailure: now}, now); got.Locked || got.RetryAfter != 10*time.Second {
+		t.Errorf("decide() without lockout = %+v, want a 10s delay", got)
+	}
+}
+
+func TestThrottlerAttempt(t *testing.T) {
+	ctx := context.Background()
+	throttler := New(NewMemoryStore(), testPolicy)
+
+	// Failed attempts use up the free ones, then have to wait
+	for i := range testPolicy.FreeAttempts + 1 {
+		decision, err := throttler.Attempt(ctx, "account:a")
+		if err != nil || !decision.Allowed() {
+			t.Fatalf("attempt %d: Attempt() = %+v, %v, want allowed", i+1, decision, err)
+		}
+	}
+	decision, err := throttler.Attempt(ctx, "account:a")
+	if err != nil || decision.Allowed() {
+		t.Fatalf("Attempt() after the free attempts = %+v, %v, want a wait", decision, err)
+	}
+
+	// Waiting attempts are not counted
+	attempts, err := throttler.store.Get(ctx, "account:a")
+	if err != nil || attempts.Failures != testPolicy.FreeAttempts+1 {
+		t.Errorf("failures = %d, %v, want %d", attempts.Failures, err, testPolicy.FreeAttempts+1)
+	}
+
+	// Other keys are unaffected
+	if decision, err := throttler.Attempt(ctx, "account:b"); err != nil || !decision.Allowed() {
+		t.Errorf("Attempt() for another key = %+v, %v, want allowed", decision, err)
+	}
+
+	if err := throttler.Reset(ctx, "account:a"); err != nil {
+		t.Fatal(err)
+	}
+	if decision, err := throttler.Attempt(ctx, "account:a"); err != nil || !decision.Allowed() {
+		t.Errorf("Attempt() after Reset = %+v, %v, want allowed", decision, err)
+	}
+}
+
+func TestThrottlerRelease(t *testing.T) {
+	ctx := context.Background()
+	throttler := New(NewMemoryStore(), testPolicy)
+
+	// Successful attempts give their reservation back, so they never
+	// add up to a delay
+	for i := range 10 * testPolicy.FreeAttempts {
+		decision, err := throttler.Attempt(ctx, "ip:a")
+		if err != nil || !decision.Allowed() {
+			t.Fatalf("attempt %d: Attempt() = %+v, %v, want allowed", i+1, decision, err)
+		}
+		if err := throttler.Release(ctx, "ip:a"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Releasing never takes the count below zero
+	if err := throttler.Release(ctx, "ip:a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := throttler.Release(ctx, "ip:unknown"); err != nil {
+		t.Fatal(err)
+	}
+	attempts, err := throttler.store.Get(ctx, "ip:a")
+	if err != nil || attempts.Failures != 0 {
+		t.Errorf("failures = %d, %v, want 0", attempts.Failures, err)
+	}
+}
+
+func TestThrottlerParallelAttempts(t *testing.T) {
+	ctx := context.Background()
+	policy := testPolicy
+	policy.BaseDelay = time.Hour
+	policy.MaxDelay = time.Hour
+	throttler := New(NewMemoryStore(), policy)
+
+	// Guesses made at once must not all go ahead on the same count
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	allowed := 0
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			decision, err := throttler.Attempt(ctx, "account:a")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if decision.Allowed() {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if want := policy.FreeAttempts + 1; allowed != want {
+		t.Errorf("%d parallel attempts went ahead, want %d", allowed, want)
+	}
+}
+
+func TestMemoryStoreWindow(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := range 3 {
+		now := start.Add(time.Duration(i) * time.Minute)
+		if _, err := store.Fail(ctx, "key", now, now.Add(-time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		after time.Duration
+		want  int
+	}{
+		{"within the window", 30 * time.Minute, 4},
+		{"after the window", 3 * time.Hour, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := start.Add(2*time.Minute + tt.after)
+			attempts, err := store.Fail(ctx, "key", now, now.Add(-time.Hour))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if attempts.Failures != tt.want || !attempts.LastFailure.Equal(now) {
+				t.Errorf("Fail() = %+v, want %d failures at %s", attempts, tt.want, now)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package webhooks
+
+import (
+	"bytes"
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Headers sent with every delivery. The signature is made with
+// auth.SignWebhook, so receivers check it with auth.VerifyWebhook.
+const (
+	SignatureHeader = "Chirpy-Signature"
+	EventIDHeader   = "Chirpy-Event-Id"
+	EventTypeHeader = "Chirpy-Event-Type"
+)
+
+const (
+	DefaultMaxAttempts = 8
+	DefaultBatchSize   = 50
+	requestTimeout     = 10 * time.Second
+	firstRetry         = 30 * time.Second
+	maxRetry           = 6 * time.Hour
+)
+
+// Waits 30s after the first failed attempt, doubling up to 6 hours
+func Backoff(attempts int) time.Duration {
+	delay := firstRetry
+	for i := 1; i < attempts && delay < maxRetry; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetry)
+}
+
+// A delivery due to be sent
+type Delivery struct {
+	ID        uuid.UUID
+	EventID   uuid.UUID
+	EventType string
+	Payload   []byte
+	URL       string
+	Secret    string
+}
+
+// The outcome of sending a delivery once
+type Attempt struct {
+	// Zero when no response was received
+	StatusCode int
+	Err        error
+	Duration   time.Duration
+}
+
+// Endpoints accept a delivery by answering with any 2xx status
+func (a Attempt) OK() bool {
+	return a.Err == nil && a.StatusCode >= 200 && a.StatusCode < 300
+}
+
+// Posts the delivery's payload to its endpoint, signed with its secret
+func Send(ctx context.Context, client *http.Client, delivery Delivery) Attempt {
+	start := time.Now()
+	attempt := func(status int, err error) Attempt {
+		return Attempt{StatusCode: status, Err: err, Duration: time.Since(start)}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return attempt(0, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Chirpy-Webhooks/1.0")
+	req.Header.Set(EventIDHeader, delivery.EventID.String())
+	req.Header.Set(EventTypeHeader, delivery.EventType)
+	req.Header.Set(SignatureHeader, auth.SignWebhook(delivery.Secret, delivery.Payload, time.Now()))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return attempt(0, err)
+	}
+	defer resp.Body.Close()
+	// Drained so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return attempt(resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status))
+	}
+	return attempt(resp.StatusCode, nil)
+}
+
+// The delivery queue, implemented by *database.Queries
+type Queue interface {
+	// Leases up to pageLimit due deliveries to the caller
+	ClaimWebhookDeliveries(ctx context.Context, pageLimit int32) ([]database.ClaimWebhookDeliveriesRow, error)
+	RecordWebhookDeliveryAttempt(ctx context.Context, arg database.RecordWebhookDeliveryAttemptParams) error
+	FinishWebhookDeliveryAttempt(ctx context.Context, arg database.FinishWebhookDeliveryAttemptParams) error
+}
+
+// Sends queued deliveries. Any number of dispatchers can share the queue,
+// a claimed delivery is leased to one of them for a minute.
+type Dispatcher struct {
+	Queries Queue
+	// Sends to the endpoints of subscriptions admins made
+	Client *http.Client
+	// Sends to the endpoints developers registered
+	PublicClient *http.Client
+	MaxAttempts  int
+	BatchSize    int32
+}
+
+func NewDispatcher(queries Queue) *Dispatcher {
+	return &Dispatcher{
+		Queries:      queries,
+		Client:       &http.Client{Timeout: requestTimeout},
+		PublicClient: NewPublicClient(),
+		MaxAttempts:  DefaultMaxAttempts,
+		BatchSize:    DefaultBatchSize,
+	}
+}
+
+// Sends one batch of due deliveries concurrently, reporting how many were
+// claimed
+func (d *Dispatcher) DeliverDue(ctx context.Context) (int, error) {
+	due, err := d.Queries.ClaimWebhookDeliveries(ctx, d.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(due))
+	for _, row := range due {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- d.deliver(ctx, row)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return len(due), err
+		}
+	}
+	return len(due), nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, row database.ClaimWebhookDeliveriesRow) error {
+	client := d.Client
+	if row.OwnerID.Valid {
+		client = d.PublicClient
+	}
+
+	attempt := Send(ctx, client, Delivery{
+		ID:        row.ID,
+		EventID:   row.EventID,
+		EventType: row.EventType,
+		Payload:   row.Payload,
+		URL:       row.Url,
+		Secret:    row.Secret,
+	})
+
+	params := database.RecordWebhookDeliveryAttemptParams{
+		DeliveryID: row.ID,
+		DurationMs: int32(attempt.Duration.Milliseconds()),
+	}
+	if attempt.StatusCode != 0 {
+		params.ResponseStatus = sql.NullInt32{Int32: int32(attempt.StatusCode), Valid: true}
+	}
+	if attempt.Err != nil {
+		params.Error = sql.NullString{String: attempt.Err.Error(), Valid: true}
+	}
+	err := d.Queries.RecordWebhookDeliveryAttempt(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	attempts := int(row.Attempts) + 1
+	status, next := StatusPending, time.Now().Add(Backoff(attempts))
+	switch {
+	case attempt.OK():
+		status = StatusDelivered
+	case attempts >= d.MaxAttempts:
+		status = StatusDead
+	}
+
+	return d.Queries.FinishWebhookDeliveryAttempt(ctx, database.FinishWebhookDeliveryAttemptParams{
+		ID:            row.ID,
+		Status:        status,
+		NextAttemptAt: next,
+	})
+}
+
+// Delivers due deliveries every interval until ctx is done. Full batches
+// are followed straight away by the next one.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := d.DeliverDue(ctx)
+		if err != nil {
+			log.Printf("webhooks: delivering: %v", err)
+		}
+		if err == nil && n == int(d.BatchSize) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
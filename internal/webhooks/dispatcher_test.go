@@ -0,0 +1,288 @@
+package webhooks
+
+import (
+	"chirpy/internal/auth"
+	"chirpy/internal/database"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{10, 4*time.Hour + 16*time.Minute},
+		{11, 6 * time.Hour},
+		{100, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// Answers with status, checking each request is signed with secret
+func testEndpoint(t *testing.T, secret string, status int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		if err := auth.VerifyWebhook(secret, r.Header.Get(SignatureHeader), body, time.Now(), auth.WebhookTolerance); err != nil {
+			t.Errorf("delivery signature: %v", err)
+		}
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("delivery sent as %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if r.Header.Get(EventTypeHeader) != "chirp.created" {
+			t.Errorf("%s = %q, want chirp.created", EventTypeHeader, r.Header.Get(EventTypeHeader))
+		}
+		if _, err := uuid.Parse(r.Header.Get(EventIDHeader)); err != nil {
+			t.Errorf("%s = %q, want an event ID", EventIDHeader, r.Header.Get(EventIDHeader))
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSend(t *testing.T) {
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+		wantOK     bool
+	}{
+		{"accepted", testEndpoint(t, "whsec", http.StatusOK).URL, http.StatusOK, true},
+		{"accepted with no content", testEndpoint(t, "whsec", http.StatusNoContent).URL, http.StatusNoContent, true},
+		{"redirected", testEndpoint(t, "whsec", http.StatusNotModified).URL, http.StatusNotModified, false},
+		{"refused", testEndpoint(t, "whsec", http.StatusGone).URL, http.StatusGone, false},
+		{"failing", testEndpoint(t, "whsec", http.StatusInternalServerError).URL, http.StatusInternalServerError, false},
+		{"unreachable", closed.URL, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempt := Send(context.Background(), http.DefaultClient, Delivery{
+				ID:        uuid.New(),
+				EventID:   uuid.New(),
+				EventType: "chirp.created",
+				Payload:   []byte(`{"type":"chirp.created"}`),
+				URL:       tt.url,
+				Secret:    "whsec",
+			})
+			if attempt.StatusCode != tt.wantStatus || attempt.OK() != tt.wantOK {
+				t.Errorf("Send() = %+v, want status %d, OK %v", attempt, tt.wantStatus, tt.wantOK)
+			}
+			if !tt.wantOK && attempt.Err == nil {
+				t.Error("a failed attempt has no error")
+			}
+		})
+	}
+}
+
+// An in-memory Queue handing out its deliveries once
+type fakeQueue struct {
+	mu       sync.Mutex
+	due      []database.ClaimWebhookDeliveriesRow
+	claimErr error
+	attempts map[uuid.UUID]database.RecordWebhookDeliveryAttemptParams
+	finished map[uuid.UUID]database.FinishWebhookDeliveryAttemptParams
+}
+
+func (q *fakeQueue) ClaimWebhookDeliveries(ctx context.Context, pageLimit int32) ([]database.ClaimWebhookDeliveriesRow, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.claimErr != nil {
+		return nil, q.claimErr
+	}
+	n := min(int(pageLimit), len(q.due))
+	claimed := q.due[:n]
+	q.due = q.due[n:]
+	return claimed, nil
+}
+
+func (q *fakeQueue) RecordWebhookDeliveryAttempt(ctx context.Context, arg database.RecordWebhookDeliveryAttemptParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.attempts[arg.DeliveryID] = arg
+	return nil
+}
+
+func (q *fakeQueue) FinishWebhookDeliveryAttempt(ctx context.Context, arg database.FinishWebhookDeliveryAttemptParams) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.finished[arg.ID] = arg
+	return nil
+}
+
+func TestDeliverDue(t *testing.T) {
+	ok := testEndpoint(t, "whsec", http.StatusOK)
+	failing := testEndpoint(t, "whsec", http.StatusInternalServerError)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	tests := []struct {
+		name       string
+		url        string
+		attempts   int32
+		wantStatus string
+		// The response status recorded, zero for none
+		wantResponse int32
+		// The delay before the next attempt of a pending delivery
+		wantRetry time.Duration
+	}{
+		{"delivered", ok.URL, 0, StatusDelivered, http.StatusOK, 0},
+		{"delivered on a retry", ok.URL, 5, StatusDelivered, http.StatusOK, 0},
+		{"first failure", failing.URL, 0, StatusPending, http.StatusInternalServerError, 30 * time.Second},
+		{"third failure", failing.URL, 2, StatusPending, http.StatusInternalServerError, 2 * time.Minute},
+		{"unreachable", closed.URL, 1, StatusPending, 0, time.Minute},
+		{"last attempt", failing.URL, DefaultMaxAttempts - 1, StatusDead, http.StatusInternalServerError, 0},
+		{"last attempt unreachable", closed.URL, DefaultMaxAttempts - 1, StatusDead, 0, 0},
+	}
+
+	queue := &fakeQueue{
+		attempts: map[uuid.UUID]database.RecordWebhookDeliveryAttemptParams{},
+		finished: map[uuid.UUID]database.FinishWebhookDeliveryAttemptParams{},
+	}
+	for _, tt := range tests {
+		queue.due = append(queue.due, database.ClaimWebhookDeliveriesRow{
+			ID:        uuid.New(),
+			EventID:   uuid.New(),
+			EventType: "chirp.created",
+			Payload:   []byte(`{"type":"chirp.created"}`),
+			Attempts:  tt.attempts,
+			Url:       tt.url,
+			Secret:    "whsec",
+		})
+	}
+	rows := queue.due
+
+	dispatcher := NewDispatcher(queue)
+	start := time.Now()
+	n, err := dispatcher.DeliverDue(context.Background())
+	if err != nil || n != len(tests) {
+		t.Fatalf("DeliverDue() = %d, %v, want %d", n, err, len(tests))
+	}
+	end := time.Now()
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id := rows[i].ID
+			attempt, ok := queue.attempts[id]
+			if !ok {
+				t.Fatal("no attempt was recorded")
+			}
+			if attempt.ResponseStatus.Int32 != tt.wantResponse || attempt.ResponseStatus.Valid != (tt.wantResponse != 0) {
+				t.Errorf("recorded response status %+v, want %d", attempt.ResponseStatus, tt.wantResponse)
+			}
+			if attempt.Error.Valid != (tt.wantStatus != StatusDelivered) {
+				t.Errorf("recorded error %+v on a %s delivery", attempt.Error, tt.wantStatus)
+			}
+
+			finished, ok := queue.finished[id]
+			if !ok {
+				t.Fatal("the attempt was not finished")
+			}
+			if finished.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", finished.Status, tt.wantStatus)
+			}
+			if tt.wantStatus == StatusPending {
+				next := finished.NextAttemptAt
+				if next.Before(start.Add(tt.wantRetry)) || next.After(end.Add(tt.wantRetry)) {
+					t.Errorf("next attempt in %s, want %s", next.Sub(start), tt.wantRetry)
+				}
+			}
+		})
+	}
+
+	// Claimed deliveries are not handed out again
+	if n, err := dispatcher.DeliverDue(context.Background()); n != 0 || err != nil {
+		t.Errorf("DeliverDue() on an empty queue = %d, %v, want 0", n, err)
+	}
+}
+
+func TestDeliverDueBatches(t *testing.T) {
+	ok := testEndpoint(t, "whsec", http.StatusOK)
+	queue := &fakeQueue{
+		attempts: map[uuid.UUID]database.RecordWebhookDeliveryAttemptParams{},
+		finished: map[uuid.UUID]database.FinishWebhookDeliveryAttemptParams{},
+	}
+	for range 5 {
+		queue.due = append(queue.due, database.ClaimWebhookDeliveriesRow{
+			ID:        uuid.New(),
+			EventID:   uuid.New(),
+			EventType: "chirp.created",
+			Payload:   []byte(`{}`),
+			Url:       ok.URL,
+			Secret:    "whsec",
+		})
+	}
+
+	dispatcher := NewDispatcher(queue)
+	dispatcher.BatchSize = 3
+	for _, want := range []int{3, 2, 0} {
+		if n, err := dispatcher.DeliverDue(context.Background()); n != want || err != nil {
+			t.Errorf("DeliverDue() = %d, %v, want %d", n, err, want)
+		}
+	}
+	if len(queue.finished) != 5 {
+		t.Errorf("%d deliveries finished, want 5", len(queue.finished))
+	}
+
+	queue.claimErr = errors.New("connection refused")
+	if _, err := dispatcher.DeliverDue(context.Background()); !errors.Is(err, queue.claimErr) {
+		t.Errorf("DeliverDue() error = %v, want the claim error", err)
+	}
+}
+
+func TestDeliverDueOwned(t *testing.T) {
+	ok := testEndpoint(t, "whsec", http.StatusOK)
+	queue := &fakeQueue{
+		attempts: map[uuid.UUID]database.RecordWebhookDeliveryAttemptParams{},
+		finished: map[uuid.UUID]database.FinishWebhookDeliveryAttemptParams{},
+	}
+	admins := database.ClaimWebhookDeliveriesRow{
+		ID:        uuid.New(),
+		EventID:   uuid.New(),
+		EventType: "chirp.created",
+		Payload:   []byte(`{}`),
+		Url:       ok.URL,
+		Secret:    "whsec",
+	}
+	developers := admins
+	developers.ID = uuid.New()
+	developers.OwnerID = uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	queue.due = append(queue.due, admins, developers)
+
+	dispatcher := NewDispatcher(queue)
+	if n, err := dispatcher.DeliverDue(context.Background()); n != 2 || err != nil {
+		t.Fatalf("DeliverDue() = %d, %v, want 2", n, err)
+	}
+	// The test endpoint listens on a loopback address
+	if status := queue.finished[admins.ID].Status; status != StatusDelivered {
+		t.Errorf("admin's delivery status = %q, want %q", status, StatusDelivered)
+	}
+	if status := queue.finished[developers.ID].Status; status != StatusPending {
+		t.Errorf("developer's delivery status = %q, want %q", status, StatusPending)
+	}
+	if attempt := queue.attempts[developers.ID]; attempt.ResponseStatus.Valid || !attempt.Error.Valid {
+		t.Errorf("developer's delivery attempt = %+v, want it refused", attempt)
+	}
+}
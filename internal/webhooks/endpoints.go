@@ -0,0 +1,63 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// Endpoints developers register must be on the public internet, or
+// subscriptions could be used to reach into Chirpy's own network
+var errPrivateAddress = errors.New("webhook endpoints must have a public address")
+
+// 100.64.0.0/10, used between carriers and their customers
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+func publicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// Checks that every address the endpoint's host resolves to is public
+func CheckEndpoint(ctx context.Context, endpoint *url.URL) error {
+	host := endpoint.Hostname()
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("cannot resolve %s", host)
+	}
+	for _, addr := range addrs {
+		if !publicAddress(addr) {
+			return errPrivateAddress
+		}
+	}
+	return nil
+}
+
+// A client that only connects to public addresses. The address is checked
+// as the connection is made, so a host that resolved to a public address
+// when its subscription was checked cannot be pointed elsewhere later.
+func NewPublicClient() *http.Client {
+	dialer := &net.Dialer{
+		Control: func(network, address string, c syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !publicAddress(addrPort.Addr()) {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would be the only address checked
+	transport.Proxy = nil
+	return &http.Client{Timeout: requestTimeout, Transport: transport}
+}
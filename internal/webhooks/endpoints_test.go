@@ -0,0 +1,79 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := publicAddress(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("publicAddress(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestCheckEndpoint(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{"https://93.184.216.34/hooks", false},
+		{"https://[2606:2800:220:1:248:1893:25c8:1946]:8443/hooks", false},
+		{"http://127.0.0.1:8080/hooks", true},
+		{"http://[::1]/hooks", true},
+		{"http://169.254.169.254/latest/meta-data", true},
+		{"http://localhost/hooks", true},
+	}
+	for _, tt := range tests {
+		endpoint, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = CheckEndpoint(context.Background(), endpoint)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckEndpoint(%s) error = %v, want error %v", tt.url, err, tt.wantErr)
+		}
+	}
+}
+
+func TestPublicClient(t *testing.T) {
+	endpoint := testEndpoint(t, "whsec", http.StatusOK)
+	attempt := Send(context.Background(), NewPublicClient(), Delivery{
+		ID:        uuid.New(),
+		EventID:   uuid.New(),
+		EventType: "chirp.created",
+		Payload:   []byte(`{}`),
+		URL:       endpoint.URL,
+		Secret:    "whsec",
+	})
+	if attempt.OK() || !errors.Is(attempt.Err, errPrivateAddress) {
+		t.Errorf("Send() to %s = %+v, want it refused", endpoint.URL, attempt)
+	}
+}
@@ -0,0 +1,78 @@
+// Package webhooks delivers Chirpy's events to the endpoints developers
+// subscribe to them. Deliveries are queued in Postgres as the events come
+// off the event bus, signed with the subscription's secret and retried
+// with exponential backoff until the endpoint accepts them or they die.
+package webhooks
+
+import (
+	"chirpy/internal/database"
+	"chirpy/internal/events"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Events subscriptions can ask for
+var EventTypes = []string{
+	events.TypeChirpCreated,
+	events.TypeChirpDeleted,
+	events.TypeChirpRestored,
+	events.TypeUserCreated,
+	events.TypeUserUpgraded,
+}
+
+// Delivery states
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	// Every attempt failed, the delivery is only retried by hand
+	StatusDead = "dead"
+)
+
+// The body of every delivery
+type Event struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Subscribes to the event bus, queueing a delivery of each event for every
+// subscription that wants it. An event handed over twice is only queued
+// once, deliveries are unique by event.
+func Subscribe(bus *events.Bus, queries *database.Queries) {
+	bus.Subscribe("webhooks", func(ctx context.Context, event events.Envelope) error {
+		return enqueue(ctx, queries, event)
+	})
+}
+
+func enqueue(ctx context.Context, queries *database.Queries, event events.Envelope) error {
+	payload, err := json.Marshal(Event{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt.UTC(),
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	return queries.EnqueueWebhookDeliveries(ctx, database.EnqueueWebhookDeliveriesParams{
+		EventID:   event.ID,
+		EventType: event.Type,
+		Payload:   payload,
+		PrivateTo: privateTo(event),
+	})
+}
+
+// The user an event is private to. Developers' subscriptions only get
+// private events about themselves; admins' subscriptions get them all.
+func privateTo(event events.Envelope) uuid.NullUUID {
+	switch e := event.Event.(type) {
+	case events.UserUpgraded:
+		return uuid.NullUUID{UUID: e.UserID, Valid: true}
+	}
+	return uuid.NullUUID{}
+}
@@ -0,0 +1,12 @@
+-- name: SearchChirps :many
+SELECT chirps.*,
+    ts_rank(to_tsvector('english', chirps.body), tsq) AS rank,
+    ts_headline('english', translate(chirps.body, E'\x02\x03', ''), tsq, E'StartSel=\x02, StopSel=\x03, MaxFragments=2')::text AS snippet
+FROM chirps,
+    to_tsquery('english', sqlc.arg(query)) tsq
+WHERE to_tsvector('english', chirps.body) @@ tsq
+AND chirps.deleted_at IS NULL
+AND (chirps.status = 'published' OR chirps.user_id = sqlc.narg(viewer_id))
+AND (sqlc.narg(author_id)::uuid IS NULL OR chirps.user_id = sqlc.narg(author_id))
+ORDER BY rank DESC, chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_limit) OFFSET sqlc.arg(page_offset);
@@ -1,38 +1,168 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, in_reply_to, status, published_at)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3,
+    $4,
+    CASE WHEN $4 = 'published' THEN NOW() END
 )
 RETURNING *;
 
 -- name: DropChirps :exec
 DELETE FROM chirps;
 
--- name: GetAllChirpsAsc :many
-SELECT * FROM chirps 
-ORDER BY created_at ASC;
+-- name: GetChirpsAfterCursor :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (status = 'published' OR user_id = sqlc.narg(viewer_id))
+AND (sqlc.narg(author_id)::uuid IS NULL OR user_id = sqlc.narg(author_id))
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_limit);
 
--- name: GetAllChirpsDesc :many
-SELECT * FROM chirps 
-ORDER BY created_at DESC;
+-- name: GetChirpsBeforeCursor :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (status = 'published' OR user_id = sqlc.narg(viewer_id))
+AND (sqlc.narg(author_id)::uuid IS NULL OR user_id = sqlc.narg(author_id))
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
 
 -- name: GetChirpById :one
 SELECT * FROM chirps 
 WHERE id = $1;
 
--- name: GetChirpsByUserIdAsc :many
+-- name: DeleteChirpById :exec
+DELETE FROM chirps
+WHERE
+id = $1;
+
+
+-- name: TombstoneChirp :exec
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: ChirpHasReplies :one
+SELECT EXISTS (
+    SELECT 1 FROM chirps WHERE in_reply_to = sqlc.arg(chirp_id)::uuid
+);
+
+-- name: GetChirpsByIds :many
 SELECT * FROM chirps
-WHERE user_id = $1 ORDER BY created_at ASC;
+WHERE id = ANY(sqlc.arg(chirp_ids)::uuid[]);
+
+-- name: GetChirpEngagement :many
+SELECT chirps.id,
+    (SELECT COUNT(*) FROM chirps replies
+        WHERE replies.in_reply_to = chirps.id AND replies.deleted_at IS NULL
+        AND replies.status = 'published') AS reply_count,
+    (SELECT COUNT(*) FROM likes
+        WHERE likes.chirp_id = chirps.id) AS like_count,
+    (SELECT COUNT(*) FROM chirps rechirps
+        WHERE rechirps.rechirp_of = chirps.id) AS rechirp_count,
+    EXISTS (SELECT 1 FROM likes
+        WHERE likes.chirp_id = chirps.id AND likes.user_id = sqlc.narg(viewer_id)) AS liked_by_viewer,
+    EXISTS (SELECT 1 FROM chirps rechirps
+        WHERE rechirps.rechirp_of = chirps.id AND rechirps.user_id = sqlc.narg(viewer_id)) AS rechirped_by_viewer
+FROM chirps
+WHERE chirps.id = ANY(sqlc.arg(chirp_ids)::uuid[]);
 
--- name: GetChirpsByUserIdDesc :many
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of, published_at)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    '',
+    sqlc.arg(user_id),
+    sqlc.arg(rechirp_of)::uuid,
+    NOW()
+)
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
+RETURNING *;
+
+-- name: GetRechirp :one
 SELECT * FROM chirps
-WHERE user_id = $1 ORDER BY created_at DESC;
+WHERE user_id = sqlc.arg(user_id) AND rechirp_of = sqlc.arg(rechirp_of)::uuid;
 
--- name: DeleteChirpById :exec
+-- name: DeleteRechirp :exec
 DELETE FROM chirps
-WHERE
-id = $1;
\ No newline at end of file
+WHERE user_id = sqlc.arg(user_id) AND rechirp_of = sqlc.arg(rechirp_of)::uuid;
+
+-- name: DeleteRechirpsOf :exec
+DELETE FROM chirps
+WHERE rechirp_of = sqlc.arg(chirp_id)::uuid;
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT chirps.*, 1 AS depth FROM chirps
+    WHERE chirps.id = (SELECT in_reply_to FROM chirps WHERE chirps.id = $1)
+    UNION ALL
+    SELECT chirps.*, ancestors.depth + 1 FROM chirps
+    JOIN ancestors ON chirps.id = ancestors.in_reply_to
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM ancestors
+ORDER BY depth DESC;
+
+-- name: GetChirpDescendantsAfterCursor :many
+WITH RECURSIVE descendants AS (
+    SELECT chirps.* FROM chirps WHERE chirps.in_reply_to = sqlc.arg(root_id)::uuid
+    UNION ALL
+    SELECT chirps.* FROM chirps
+    JOIN descendants ON chirps.in_reply_to = descendants.id
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM descendants
+WHERE (status = 'published' OR user_id = sqlc.narg(viewer_id)::uuid)
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetChirpDescendantsBeforeCursor :many
+WITH RECURSIVE descendants AS (
+    SELECT chirps.* FROM chirps WHERE chirps.in_reply_to = sqlc.arg(root_id)::uuid
+    UNION ALL
+    SELECT chirps.* FROM chirps
+    JOIN descendants ON chirps.in_reply_to = descendants.id
+)
+SELECT id, created_at, updated_at, body, user_id, in_reply_to, deleted_at, rechirp_of, status, published_at FROM descendants
+WHERE (status = 'published' OR user_id = sqlc.narg(viewer_id)::uuid)
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetChirpByIdForUpdate :one
+SELECT * FROM chirps WHERE id = $1 FOR UPDATE;
+
+-- name: SetChirpStatus :one
+UPDATE chirps
+SET status = $1,
+    published_at = COALESCE(published_at, CASE WHEN $1 = 'published' THEN NOW() END),
+    updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: GetChirpsByStatusAfterCursor :many
+SELECT * FROM chirps
+WHERE status = sqlc.arg(status) AND deleted_at IS NULL
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetChirpsByStatusBeforeCursor :many
+SELECT * FROM chirps
+WHERE status = sqlc.arg(status) AND deleted_at IS NULL
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
@@ -0,0 +1,18 @@
+-- name: UseToken :execrows
+INSERT INTO used_tokens (id, used_at, expires_at)
+VALUES ($1, NOW(), $2)
+ON CONFLICT (id) DO NOTHING;
+
+-- name: DeleteExpiredUsedTokens :exec
+DELETE FROM used_tokens
+WHERE expires_at < NOW();
+
+-- name: MarkEmailVerified :execrows
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email = $2;
+
+-- name: UpdatePassword :exec
+UPDATE users
+SET hashed_password = $1, updated_at = NOW()
+WHERE id = $2;
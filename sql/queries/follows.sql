@@ -0,0 +1,70 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowersAfterCursor :many
+SELECT follower_id, created_at FROM follows
+WHERE followee_id = sqlc.arg(user_id)
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, follower_id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at ASC, follower_id ASC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetFollowersBeforeCursor :many
+SELECT follower_id, created_at FROM follows
+WHERE followee_id = sqlc.arg(user_id)
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, follower_id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetFollowingAfterCursor :many
+SELECT followee_id, created_at FROM follows
+WHERE follower_id = sqlc.arg(user_id)
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, followee_id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at ASC, followee_id ASC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetFollowingBeforeCursor :many
+SELECT followee_id, created_at FROM follows
+WHERE follower_id = sqlc.arg(user_id)
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, followee_id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetTimelineAfterCursor :many
+SELECT chirps.* FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg(follower_id)
+AND chirps.deleted_at IS NULL
+AND (chirps.status = 'published' OR chirps.user_id = sqlc.narg(viewer_id))
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetTimelineBeforeCursor :many
+SELECT chirps.* FROM chirps
+JOIN follows ON follows.followee_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg(follower_id)
+AND chirps.deleted_at IS NULL
+AND (chirps.status = 'published' OR chirps.user_id = sqlc.narg(viewer_id))
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (chirps.created_at, chirps.id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetFolloweeIDs :many
+SELECT followee_id FROM follows
+WHERE follower_id = $1;
@@ -0,0 +1,49 @@
+-- name: CreateNotification :exec
+INSERT INTO notifications (id, created_at, user_id, type, actor_id, chirp_id)
+SELECT gen_random_uuid(), NOW(), users.id, sqlc.arg(type)::text, sqlc.arg(actor_id)::uuid, sqlc.arg(chirp_id)::uuid
+FROM users
+WHERE users.id = sqlc.arg(user_id)::uuid
+AND users.id <> sqlc.arg(actor_id)::uuid
+AND NOT (sqlc.arg(type)::text = ANY(users.muted_notifications))
+AND EXISTS (SELECT 1 FROM chirps WHERE chirps.id = sqlc.arg(chirp_id)::uuid)
+AND EXISTS (SELECT 1 FROM users actors WHERE actors.id = sqlc.arg(actor_id)::uuid)
+ON CONFLICT (user_id, type, actor_id, chirp_id) DO NOTHING;
+
+-- name: GetNotificationsAfterCursor :many
+SELECT notifications.*, users.handle AS actor_handle FROM notifications
+JOIN users ON users.id = notifications.actor_id
+WHERE notifications.user_id = sqlc.arg(user_id)
+AND (NOT sqlc.arg(unread_only)::bool OR notifications.read_at IS NULL)
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (notifications.created_at, notifications.id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY notifications.created_at ASC, notifications.id ASC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetNotificationsBeforeCursor :many
+SELECT notifications.*, users.handle AS actor_handle FROM notifications
+JOIN users ON users.id = notifications.actor_id
+WHERE notifications.user_id = sqlc.arg(user_id)
+AND (NOT sqlc.arg(unread_only)::bool OR notifications.read_at IS NULL)
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (notifications.created_at, notifications.id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY notifications.created_at DESC, notifications.id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications
+SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications
+SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: SetMutedNotifications :exec
+UPDATE users
+SET muted_notifications = $2, updated_at = NOW()
+WHERE id = $1;
@@ -0,0 +1,46 @@
+-- name: InsertOutboxEvent :exec
+INSERT INTO outbox_events (id, created_at, type, payload)
+VALUES (
+    sqlc.arg(id),
+    NOW(),
+    sqlc.arg(type),
+    sqlc.arg(payload)::jsonb
+);
+
+-- name: GetOutboxEventsAfter :many
+SELECT * FROM outbox_events
+WHERE (transaction_id, seq) > (sqlc.arg(transaction_id)::bigint, sqlc.arg(seq)::bigint)
+AND transaction_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+ORDER BY transaction_id, seq
+LIMIT sqlc.arg(page_limit);
+
+-- name: TryLockEventSubscriber :one
+SELECT pg_try_advisory_xact_lock(hashtext('event_subscriber:' || sqlc.arg(subscriber)::text)) AS locked;
+
+-- name: CreateEventCheckpoint :exec
+INSERT INTO event_checkpoints (subscriber, updated_at, transaction_id, seq)
+VALUES ($1, NOW(), 0, 0)
+ON CONFLICT (subscriber) DO NOTHING;
+
+-- name: GetEventCheckpoint :one
+SELECT * FROM event_checkpoints
+WHERE subscriber = $1;
+
+-- name: SaveEventCheckpoint :exec
+UPDATE event_checkpoints
+SET transaction_id = $2, seq = $3, updated_at = NOW()
+WHERE subscriber = $1;
+
+-- name: DeleteHandledOutboxEvents :execrows
+DELETE FROM outbox_events o
+WHERE o.created_at < $1
+AND NOT EXISTS (
+    SELECT 1 FROM event_checkpoints c
+    WHERE (c.transaction_id, c.seq) < (o.transaction_id, o.seq)
+);
+
+-- name: GetLatestOutboxPosition :one
+SELECT transaction_id, seq FROM outbox_events
+WHERE transaction_id < pg_snapshot_xmin(pg_current_snapshot())::text::bigint
+ORDER BY transaction_id DESC, seq DESC
+LIMIT 1;
@@ -0,0 +1,106 @@
+-- name: CreateWebhookSubscription :one
+INSERT INTO webhook_subscriptions (id, created_at, updated_at, url, event_types, secret, owner_id)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+RETURNING *;
+
+-- name: GetWebhookSubscriptions :many
+SELECT * FROM webhook_subscriptions
+WHERE sqlc.narg(owner_id)::uuid IS NULL OR owner_id = sqlc.narg(owner_id)
+ORDER BY created_at DESC;
+
+-- name: GetWebhookSubscription :one
+SELECT * FROM webhook_subscriptions
+WHERE id = sqlc.arg(id)
+AND (sqlc.narg(owner_id)::uuid IS NULL OR owner_id = sqlc.narg(owner_id));
+
+-- name: DeleteWebhookSubscription :execrows
+DELETE FROM webhook_subscriptions
+WHERE id = sqlc.arg(id)
+AND (sqlc.narg(owner_id)::uuid IS NULL OR owner_id = sqlc.narg(owner_id));
+
+-- name: EnqueueWebhookDeliveries :exec
+INSERT INTO webhook_deliveries (
+    id, created_at, updated_at, subscription_id, event_id, event_type,
+    payload, status, next_attempt_at
+)
+SELECT gen_random_uuid(), NOW(), NOW(), id, sqlc.arg(event_id), sqlc.arg(event_type), sqlc.arg(payload)::jsonb, 'pending', NOW()
+FROM webhook_subscriptions
+WHERE sqlc.arg(event_type) = ANY(event_types)
+AND (owner_id IS NULL OR sqlc.narg(private_to)::uuid IS NULL OR owner_id = sqlc.narg(private_to))
+ON CONFLICT (subscription_id, event_id) DO NOTHING;
+
+-- name: ClaimWebhookDeliveries :many
+UPDATE webhook_deliveries d
+SET next_attempt_at = NOW() + INTERVAL '1 minute', updated_at = NOW()
+FROM webhook_subscriptions s
+WHERE s.id = d.subscription_id
+AND d.id IN (
+    SELECT id FROM webhook_deliveries
+    WHERE status = 'pending' AND next_attempt_at <= NOW()
+    ORDER BY next_attempt_at
+    LIMIT sqlc.arg(page_limit)
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING d.id, d.event_id, d.event_type, d.payload, d.attempts, s.url, s.secret, s.owner_id;
+
+-- name: RecordWebhookDeliveryAttempt :exec
+INSERT INTO webhook_delivery_attempts (id, created_at, delivery_id, response_status, error, duration_ms)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+);
+
+-- name: FinishWebhookDeliveryAttempt :exec
+UPDATE webhook_deliveries
+SET status = $2,
+    attempts = attempts + 1,
+    next_attempt_at = $3,
+    delivered_at = CASE WHEN $2 = 'delivered' THEN NOW() END,
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: RetryWebhookDelivery :execrows
+UPDATE webhook_deliveries
+SET status = 'pending', next_attempt_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND status = 'dead';
+
+-- name: GetWebhookDelivery :one
+SELECT webhook_deliveries.* FROM webhook_deliveries
+JOIN webhook_subscriptions ON webhook_subscriptions.id = webhook_deliveries.subscription_id
+WHERE webhook_deliveries.id = sqlc.arg(id)
+AND (sqlc.narg(owner_id)::uuid IS NULL OR webhook_subscriptions.owner_id = sqlc.narg(owner_id));
+
+-- name: GetWebhookDeliveryAttempts :many
+SELECT * FROM webhook_delivery_attempts
+WHERE delivery_id = $1
+ORDER BY created_at ASC;
+
+-- name: GetWebhookDeliveriesAfterCursor :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = sqlc.arg(subscription_id)
+AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetWebhookDeliveriesBeforeCursor :many
+SELECT * FROM webhook_deliveries
+WHERE subscription_id = sqlc.arg(subscription_id)
+AND (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
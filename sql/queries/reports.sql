@@ -0,0 +1,44 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, created_at, updated_at, chirp_id, reporter_id, reason, details)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    $3,
+    $4
+)
+ON CONFLICT (chirp_id, reporter_id) DO NOTHING
+RETURNING *;
+
+-- name: GetReportById :one
+SELECT * FROM reports
+WHERE id = $1;
+
+-- name: GetReportsAfterCursor :many
+SELECT * FROM reports
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetReportsBeforeCursor :many
+SELECT * FROM reports
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
+
+-- name: ResolveReport :one
+UPDATE reports
+SET status = $1, resolution_note = $2, resolved_at = NOW(), updated_at = NOW()
+WHERE id = $3
+RETURNING *;
+
+-- name: ResolveChirpReports :exec
+UPDATE reports
+SET status = 'actioned', resolved_at = NOW(), updated_at = NOW()
+WHERE chirp_id = $1 AND status = 'open';
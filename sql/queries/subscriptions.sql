@@ -0,0 +1,57 @@
+-- name: StartSubscription :one
+INSERT INTO subscriptions (
+    id, created_at, updated_at, user_id, plan, status,
+    current_period_start, current_period_end
+)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    $1,
+    $2,
+    'active',
+    $3,
+    $4
+)
+ON CONFLICT (user_id) DO UPDATE
+SET plan = EXCLUDED.plan,
+    status = 'active',
+    current_period_start = EXCLUDED.current_period_start,
+    current_period_end = EXCLUDED.current_period_end,
+    ended_at = NULL,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: GetSubscription :one
+SELECT * FROM subscriptions
+WHERE user_id = $1;
+
+-- name: RenewSubscription :one
+UPDATE subscriptions
+SET status = 'active',
+    current_period_start = $2,
+    current_period_end = $3,
+    ended_at = NULL,
+    updated_at = NOW()
+WHERE user_id = $1
+RETURNING *;
+
+-- name: MarkSubscriptionPastDue :one
+UPDATE subscriptions
+SET status = 'past_due', updated_at = NOW()
+WHERE user_id = $1 AND status IN ('active', 'past_due')
+RETURNING *;
+
+-- name: EndSubscription :one
+UPDATE subscriptions
+SET status = $2, ended_at = NOW(), updated_at = NOW()
+WHERE user_id = $1
+RETURNING *;
+
+-- name: HasActiveSubscription :one
+SELECT EXISTS (
+    SELECT 1 FROM subscriptions
+    WHERE user_id = sqlc.arg(user_id)
+    AND status IN ('active', 'past_due')
+    AND current_period_end > sqlc.arg(grace_cutoff)
+);
@@ -1,11 +1,12 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
     gen_random_uuid(),
     NOW(),
     NOW(),
     $1,
-    $2
+    $2,
+    $3
 )
 RETURNING *;
 
@@ -20,11 +21,40 @@ SELECT id FROM users WHERE email = $1;
 
 -- name: UpdateEmailandPassword :exec
 UPDATE users
-SET email = $1, hashed_password = $2, updated_at = NOW()
+SET email = $1, hashed_password = $2, updated_at = NOW(),
+    email_verified_at = CASE WHEN email = $1 THEN email_verified_at END
 WHERE id = $3;
 
--- name: GrantChirpyRed :exec
+-- name: GetUserById :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: UpdateHandle :exec
+UPDATE users
+SET handle = $1, updated_at = NOW()
+WHERE id = $2;
+
+-- name: GetUsersByHandles :many
+SELECT id, handle FROM users
+WHERE handle = ANY(sqlc.arg(handles)::text[]);
+
+-- name: SuspendUser :exec
+UPDATE users
+SET suspended_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: UnsuspendUser :exec
 UPDATE users
-SET is_chirpy_red = true
-WHERE
-id = $1;
+SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: SetUserRoleByEmail :one
+UPDATE users
+SET role = $1, updated_at = NOW()
+WHERE email = $2
+RETURNING *;
@@ -0,0 +1,57 @@
+-- name: RecordWebhookEvent :one
+INSERT INTO webhook_events (id, created_at, updated_at, source, event_id, event_type, payload, status)
+VALUES (
+    gen_random_uuid(),
+    NOW(),
+    NOW(),
+    sqlc.arg(source),
+    sqlc.arg(event_id),
+    sqlc.arg(event_type),
+    sqlc.arg(payload)::jsonb,
+    'received'
+)
+ON CONFLICT (source, event_id) DO UPDATE
+SET updated_at = webhook_events.updated_at
+RETURNING *;
+
+-- name: GetWebhookEvent :one
+SELECT * FROM webhook_events
+WHERE id = $1;
+
+-- name: LockWebhookEvent :one
+SELECT * FROM webhook_events
+WHERE id = $1
+FOR UPDATE;
+
+-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events
+SET status = 'processed',
+    attempts = attempts + 1,
+    last_error = NULL,
+    processed_at = NOW(),
+    updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkWebhookEventFailed :exec
+UPDATE webhook_events
+SET status = 'failed',
+    attempts = attempts + 1,
+    last_error = $2,
+    updated_at = NOW()
+WHERE id = $1 AND status <> 'processed';
+
+-- name: GetWebhookEventsAfterCursor :many
+SELECT * FROM webhook_events
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) > (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg(page_limit);
+
+-- name: GetWebhookEventsBeforeCursor :many
+SELECT * FROM webhook_events
+WHERE (sqlc.narg(status)::text IS NULL OR status = sqlc.narg(status))
+AND (sqlc.narg(cursor_created_at)::timestamp IS NULL
+    OR (created_at, id) < (sqlc.narg(cursor_created_at), sqlc.narg(cursor_id)::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg(page_limit);
@@ -0,0 +1,7 @@
+-- +goose Up
+-- An expression index rather than a stored column, so the tsvector is not
+-- returned by every query selecting chirps.*
+CREATE INDEX chirps_search_idx ON chirps USING GIN (to_tsvector('english', body));
+
+-- +goose Down
+DROP INDEX chirps_search_idx;
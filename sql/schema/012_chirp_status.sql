@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN
+status TEXT NOT NULL DEFAULT 'published'
+CONSTRAINT chirps_status_check CHECK (status IN ('published', 'held'));
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN status;
@@ -0,0 +1,35 @@
+-- +goose Up
+ALTER TABLE chirps DROP CONSTRAINT chirps_status_check;
+ALTER TABLE chirps ADD CONSTRAINT chirps_status_check
+CHECK (status IN ('published', 'held', 'hidden'));
+
+ALTER TABLE users ADD COLUMN
+suspended_at TIMESTAMP;
+
+CREATE TABLE reports (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    chirp_id UUID NOT NULL,
+    reporter_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    details TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    resolution_note TEXT,
+    resolved_at TIMESTAMP,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+    FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (chirp_id, reporter_id),
+    CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'sexual', 'misinformation', 'other')),
+    CHECK (status IN ('open', 'dismissed', 'actioned'))
+);
+
+CREATE INDEX reports_status_created_at_id_idx ON reports (status, created_at, id);
+
+-- +goose Down
+DROP TABLE reports;
+ALTER TABLE users DROP COLUMN suspended_at;
+UPDATE chirps SET status = 'held' WHERE status = 'hidden';
+ALTER TABLE chirps DROP CONSTRAINT chirps_status_check;
+ALTER TABLE chirps ADD CONSTRAINT chirps_status_check
+CHECK (status IN ('published', 'held'));
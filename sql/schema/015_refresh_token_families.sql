@@ -0,0 +1,17 @@
+-- +goose Up
+-- Every refresh token descends from a login; rotating a token adds a new
+-- member to the family and marks the old one as rotated
+ALTER TABLE refresh_tokens ADD COLUMN
+family_id UUID NOT NULL DEFAULT gen_random_uuid();
+ALTER TABLE refresh_tokens ALTER COLUMN family_id DROP DEFAULT;
+
+ALTER TABLE refresh_tokens ADD COLUMN
+rotated_at TIMESTAMP;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id);
+
+-- +goose Down
+DROP INDEX refresh_tokens_user_id_idx;
+ALTER TABLE refresh_tokens DROP COLUMN rotated_at;
+ALTER TABLE refresh_tokens DROP COLUMN family_id;
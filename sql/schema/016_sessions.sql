@@ -0,0 +1,17 @@
+-- +goose Up
+-- A session is a family of refresh tokens; the metadata is carried along
+-- to every token the family rotates into
+ALTER TABLE refresh_tokens ADD COLUMN
+user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN
+ip TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN
+label TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN
+last_used_at TIMESTAMP NOT NULL DEFAULT NOW();
+
+-- +goose Down
+ALTER TABLE refresh_tokens DROP COLUMN last_used_at;
+ALTER TABLE refresh_tokens DROP COLUMN label;
+ALTER TABLE refresh_tokens DROP COLUMN ip;
+ALTER TABLE refresh_tokens DROP COLUMN user_agent;
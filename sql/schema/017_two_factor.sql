@@ -0,0 +1,26 @@
+-- +goose Up
+-- totp_secret is set on enrollment and only enforced once totp_enabled_at
+-- is set by confirming a code. totp_last_step stops codes being replayed.
+ALTER TABLE users ADD COLUMN
+totp_secret TEXT;
+ALTER TABLE users ADD COLUMN
+totp_enabled_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN
+totp_last_step BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE recovery_codes (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX recovery_codes_user_id_idx ON recovery_codes (user_id);
+
+-- +goose Down
+DROP TABLE recovery_codes;
+ALTER TABLE users DROP COLUMN totp_last_step;
+ALTER TABLE users DROP COLUMN totp_enabled_at;
+ALTER TABLE users DROP COLUMN totp_secret;
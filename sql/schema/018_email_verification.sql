@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN
+email_verified_at TIMESTAMP;
+
+-- IDs of single use tokens that have been spent, kept until they expire
+CREATE TABLE used_tokens (
+    id UUID PRIMARY KEY,
+    used_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE used_tokens;
+ALTER TABLE users DROP COLUMN email_verified_at;
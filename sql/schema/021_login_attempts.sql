@@ -0,0 +1,10 @@
+-- +goose Up
+-- Failed logins per account or client IP, shared by every server instance
+CREATE TABLE login_attempts (
+    key TEXT PRIMARY KEY,
+    failures INTEGER NOT NULL,
+    last_failure_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE login_attempts;
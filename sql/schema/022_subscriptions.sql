@@ -0,0 +1,40 @@
+-- +goose Up
+-- A user's Chirpy Red subscription, kept up to date by Polka's webhooks.
+-- status is active, past_due (a payment failed), canceled or refunded.
+CREATE TABLE subscriptions (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL UNIQUE,
+    plan TEXT NOT NULL,
+    status TEXT NOT NULL,
+    current_period_start TIMESTAMP NOT NULL,
+    current_period_end TIMESTAMP NOT NULL,
+    ended_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- Upgrades granted before subscriptions existed start a first period now,
+-- Polka's renewals take it from there
+INSERT INTO subscriptions (
+    id, created_at, updated_at, user_id, plan, status,
+    current_period_start, current_period_end
+)
+SELECT gen_random_uuid(), NOW(), NOW(), id, 'chirpy_red', 'active', NOW(), NOW() + INTERVAL '1 month'
+FROM users
+WHERE is_chirpy_red;
+
+ALTER TABLE users DROP COLUMN is_chirpy_red;
+
+-- +goose Down
+ALTER TABLE users ADD COLUMN
+is_chirpy_red BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE users
+SET is_chirpy_red = true
+WHERE id IN (
+    SELECT user_id FROM subscriptions
+    WHERE status IN ('active', 'past_due') AND current_period_end > NOW()
+);
+
+DROP TABLE subscriptions;
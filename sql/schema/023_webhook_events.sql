@@ -0,0 +1,21 @@
+-- +goose Up
+-- Every webhook delivery received, so duplicates are only processed once
+-- and failures can be inspected and replayed.
+-- status is received, processed or failed.
+CREATE TABLE webhook_events (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    source TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    processed_at TIMESTAMP,
+    UNIQUE (source, event_id)
+);
+
+-- +goose Down
+DROP TABLE webhook_events;
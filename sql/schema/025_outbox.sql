@@ -0,0 +1,31 @@
+-- +goose Up
+-- Domain events, written in the transaction that made the change. Readers
+-- go by (transaction_id, seq): only events of transactions older than the
+-- oldest one still running are read, so an event committed late can never
+-- land behind a reader's checkpoint.
+CREATE TABLE outbox_events (
+    id UUID PRIMARY KEY,
+    seq BIGSERIAL NOT NULL,
+    transaction_id BIGINT NOT NULL DEFAULT pg_current_xact_id()::text::bigint,
+    created_at TIMESTAMP NOT NULL,
+    type TEXT NOT NULL,
+    payload JSONB NOT NULL
+);
+
+CREATE INDEX outbox_events_position_idx ON outbox_events (transaction_id, seq);
+
+-- The last event each subscriber has handled
+CREATE TABLE event_checkpoints (
+    subscriber TEXT PRIMARY KEY,
+    updated_at TIMESTAMP NOT NULL,
+    transaction_id BIGINT NOT NULL,
+    seq BIGINT NOT NULL
+);
+
+-- Events can be handed to the webhooks subscriber more than once
+CREATE UNIQUE INDEX webhook_deliveries_event_idx ON webhook_deliveries (subscription_id, event_id);
+
+-- +goose Down
+DROP INDEX webhook_deliveries_event_idx;
+DROP TABLE event_checkpoints;
+DROP TABLE outbox_events;
@@ -0,0 +1,20 @@
+-- +goose Up
+-- Wakes the servers streaming events once a transaction that wrote to the
+-- outbox commits. Notifications carry no payload, so Postgres folds those
+-- of one transaction into one.
+-- +goose StatementBegin
+CREATE FUNCTION notify_outbox_events() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('outbox_events', '');
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER outbox_events_notify
+AFTER INSERT ON outbox_events
+FOR EACH STATEMENT EXECUTE FUNCTION notify_outbox_events();
+
+-- +goose Down
+DROP TRIGGER outbox_events_notify ON outbox_events;
+DROP FUNCTION notify_outbox_events();
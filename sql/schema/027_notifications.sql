@@ -0,0 +1,28 @@
+-- +goose Up
+-- One row per thing a user is told about. Notifications are unique by
+-- what they say, so liking a chirp twice does not notify twice.
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL,
+    user_id UUID NOT NULL,
+    type TEXT NOT NULL,
+    actor_id UUID NOT NULL,
+    chirp_id UUID NOT NULL,
+    read_at TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (chirp_id) REFERENCES chirps(id) ON DELETE CASCADE,
+    UNIQUE (user_id, type, actor_id, chirp_id)
+);
+
+CREATE INDEX notifications_user_idx ON notifications (user_id, created_at, id);
+
+CREATE INDEX notifications_unread_idx ON notifications (user_id)
+WHERE read_at IS NULL;
+
+-- The notification types the user turned off
+ALTER TABLE users ADD COLUMN muted_notifications TEXT[] NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN muted_notifications;
+DROP TABLE notifications;
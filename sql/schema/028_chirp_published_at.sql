@@ -0,0 +1,12 @@
+-- +goose Up
+-- When the chirp was first published. A hidden chirp published again is
+-- announced as restored rather than created, if it was ever out.
+ALTER TABLE chirps ADD COLUMN
+published_at TIMESTAMP;
+
+-- Hidden chirps may have been held when they were hidden, but most were
+-- reported while published
+UPDATE chirps SET published_at = created_at WHERE status <> 'held';
+
+-- +goose Down
+ALTER TABLE chirps DROP COLUMN published_at;
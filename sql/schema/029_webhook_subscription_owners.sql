@@ -0,0 +1,11 @@
+-- +goose Up
+-- Developers own the subscriptions they register and only get events they
+-- may see. Subscriptions without an owner are made by admins for Chirpy's
+-- own services and get every event.
+ALTER TABLE webhook_subscriptions ADD COLUMN
+owner_id UUID REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX webhook_subscriptions_owner_idx ON webhook_subscriptions (owner_id);
+
+-- +goose Down
+ALTER TABLE webhook_subscriptions DROP COLUMN owner_id;